@@ -0,0 +1,279 @@
+package v1handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tính thời điểm chạy tiếp theo sau t
+type Schedule interface {
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// EverySchedule chạy cách đều một khoảng, tính từ lần chạy trước (như SCHEDULER_INTERVAL)
+type EverySchedule struct {
+	Every time.Duration
+}
+
+func (e EverySchedule) Next(t time.Time) time.Time {
+	return t.Add(e.Every)
+}
+
+func (e EverySchedule) String() string {
+	return "@every " + e.Every.String()
+}
+
+// CronSchedule là biểu thức cron đã parse. Mỗi field là bitmask các giá trị được phép.
+type CronSchedule struct {
+	expr     string
+	second   uint64
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool // dom là "*"/"?": khi đó chỉ xét dow (và ngược lại), như cron chuẩn
+	dowStar  bool
+	hourStar bool // field giờ có "*" (vd "*/2"): giờ lặp lại khi lùi đồng hồ vẫn chạy, như Vixie cron
+	location *time.Location
+}
+
+func (c *CronSchedule) String() string {
+	return c.expr
+}
+
+// Location trả về múi giờ dùng để tính lịch
+func (c *CronSchedule) Location() *time.Location {
+	return c.location
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	fieldSecond = cronField{"second", 0, 59, nil}
+	fieldMinute = cronField{"minute", 0, 59, nil}
+	fieldHour   = cronField{"hour", 0, 23, nil}
+	fieldDom    = cronField{"day of month", 1, 31, nil}
+	fieldMonth  = cronField{"month", 1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 cũng là Chủ nhật
+	fieldDow = cronField{"day of week", 0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseSchedule parse biểu thức lịch:
+//   - 5 field "phút giờ ngày tháng thứ" hoặc 6 field có thêm giây ở đầu
+//   - macro @yearly, @monthly, @weekly, @daily, @hourly, @every <duration> (vd "@every 90s")
+//   - tiền tố "CRON_TZ=Asia/Ho_Chi_Minh " (hoặc "TZ=") để chọn múi giờ; không có thì dùng loc
+//
+// Field hỗ trợ *, ?, danh sách "1,3", khoảng "1-5", bước "*/15" hoặc "10-50/10",
+// tên tháng/thứ (JAN, MON...). Khi cả ngày và thứ cùng bị giới hạn, khớp một trong hai là chạy.
+func ParseSchedule(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if loc == nil {
+		loc = time.Local
+	}
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		tz, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %v", name, err)
+		}
+		loc, spec = l, strings.TrimSpace(rest)
+	}
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	if strings.HasPrefix(spec, "@every") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every duration: %v", err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("@every duration must be at least 1s")
+		}
+		return EverySchedule{Every: d}, nil
+	}
+
+	expr := spec
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("unknown schedule macro %q", spec)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron expression %q must have 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	c := &CronSchedule{expr: expr, location: loc}
+	var err error
+	if c.second, err = parseCronField(fields[0], fieldSecond); err != nil {
+		return nil, err
+	}
+	if c.minute, err = parseCronField(fields[1], fieldMinute); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[2], fieldHour); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[3], fieldDom); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[4], fieldMonth); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[5], fieldDow); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1 << 0
+	}
+	c.domStar = fields[3] == "*" || fields[3] == "?"
+	c.dowStar = fields[5] == "*" || fields[5] == "?"
+	c.hourStar = strings.ContainsAny(fields[2], "*?")
+	return c, nil
+}
+
+func parseCronField(s string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = cronValue(a, f); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(b, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid %s range %q", f.name, rangePart)
+			}
+		default:
+			v, err := cronValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/10" = từ 5 tới max, bước 10
+			if !hasStep {
+				hi = v
+			}
+		}
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, stepPart)
+			}
+			step = n
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, f cronField) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s value %q (%d-%d)", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next trả về thời điểm khớp đầu tiên sau t, zero nếu không có trong 5 năm tới (vd "0 0 30 2 *").
+// Tính theo giờ địa phương của múi giờ lịch: giờ không tồn tại khi chuyển sang giờ mùa hè bị bỏ qua,
+// giờ lặp lại khi chuyển về giờ chuẩn chỉ chạy một lần nếu field giờ cố định (lịch "*" ở field giờ
+// như "*/30 * * * *" vẫn chạy ở cả hai lần).
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.In(c.location).Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+			continue
+		}
+		// Từ đây cộng theo thời gian tuyệt đối để không bị time.Date kéo lùi khi có giờ lặp lại
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second).Add(time.Hour)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(-time.Duration(t.Second()) * time.Second).Add(time.Minute)
+			continue
+		}
+		if c.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		if !c.hourStar && repeatedWallClock(t) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// repeatedWallClock: t là lần thứ hai cùng giờ địa phương xuất hiện (ngay sau khi lùi đồng hồ)
+func repeatedWallClock(t time.Time) bool {
+	_, off := t.Zone()
+	_, prevOff := t.Add(-3 * time.Hour).Zone()
+	if prevOff <= off {
+		return false
+	}
+	earlier := t.Add(-time.Duration(prevOff-off) * time.Second)
+	_, earlierOff := earlier.Zone()
+	return earlierOff == prevOff && earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute() && earlier.Second() == t.Second()
+}
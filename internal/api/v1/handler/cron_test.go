@@ -0,0 +1,124 @@
+package v1handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+		want    string // String() của lịch
+	}{
+		{spec: "0 9 * * *", want: "0 9 * * *"},
+		{spec: "*/15 0 9 * * MON-FRI", want: "*/15 0 9 * * MON-FRI"},
+		{spec: "@daily", want: "@daily"},
+		{spec: "@HOURLY", want: "@HOURLY"},
+		{spec: "@every 90s", want: "@every 1m30s"},
+		{spec: "CRON_TZ=Asia/Ho_Chi_Minh 0 8 * * *", want: "0 8 * * *"},
+		{spec: "TZ=UTC @weekly", want: "@weekly"},
+		{spec: "", wantErr: true},
+		{spec: "* * * *", wantErr: true},
+		{spec: "* * * * * * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "0 24 * * *", wantErr: true},
+		{spec: "0 0 0 * *", wantErr: true},
+		{spec: "0 0 * 13 *", wantErr: true},
+		{spec: "0 0 * * 8", wantErr: true},
+		{spec: "5-1 * * * *", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+		{spec: "@fortnightly", wantErr: true},
+		{spec: "@every 500ms", wantErr: true},
+		{spec: "@every soon", wantErr: true},
+		{spec: "CRON_TZ=Mars/Base 0 0 * * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec, time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchedule(%q) err = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err == nil && s.String() != tt.want {
+				t.Fatalf("ParseSchedule(%q).String() = %q, want %q", tt.spec, s.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		name string
+		spec string
+		loc  *time.Location
+		from string
+		want []string // các lần chạy liên tiếp (UTC); rỗng = không bao giờ chạy
+	}{
+		{name: "5 fields daily", spec: "0 9 * * *", loc: time.UTC, from: "2024-01-01T10:00:00Z",
+			want: []string{"2024-01-02T09:00:00Z", "2024-01-03T09:00:00Z"}},
+		{name: "6 fields seconds", spec: "*/20 * * * * *", loc: time.UTC, from: "2024-01-01T00:00:07Z",
+			want: []string{"2024-01-01T00:00:20Z", "2024-01-01T00:00:40Z", "2024-01-01T00:01:00Z"}},
+		{name: "exact match is not repeated", spec: "0 9 * * *", loc: time.UTC, from: "2024-01-01T09:00:00Z",
+			want: []string{"2024-01-02T09:00:00Z"}},
+		{name: "macro monthly", spec: "@monthly", loc: time.UTC, from: "2024-01-15T00:00:00Z",
+			want: []string{"2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z"}},
+		{name: "weekday names", spec: "0 0 * * SAT,sun", loc: time.UTC, from: "2024-01-01T00:00:00Z",
+			want: []string{"2024-01-06T00:00:00Z", "2024-01-07T00:00:00Z", "2024-01-13T00:00:00Z"}},
+		{name: "dom or dow", spec: "0 0 13 * 5", loc: time.UTC, from: "2024-09-10T00:00:00Z",
+			want: []string{"2024-09-13T00:00:00Z", "2024-09-20T00:00:00Z", "2024-09-27T00:00:00Z", "2024-10-04T00:00:00Z", "2024-10-11T00:00:00Z", "2024-10-13T00:00:00Z"}},
+		{name: "leap day", spec: "0 0 29 2 *", loc: time.UTC, from: "2023-01-01T00:00:00Z",
+			want: []string{"2024-02-29T00:00:00Z", "2028-02-29T00:00:00Z"}},
+		{name: "feb 30 never runs", spec: "0 0 30 2 *", loc: time.UTC, from: "2024-01-01T00:00:00Z"},
+		{name: "time zone prefix", spec: "CRON_TZ=Asia/Ho_Chi_Minh 0 8 * * *", loc: time.UTC, from: "2024-01-01T00:00:00Z",
+			want: []string{"2024-01-01T01:00:00Z", "2024-01-02T01:00:00Z"}},
+		{name: "every", spec: "@every 90s", loc: time.UTC, from: "2024-01-01T00:00:00Z",
+			want: []string{"2024-01-01T00:01:30Z", "2024-01-01T00:03:00Z"}},
+		// 2024-03-10 02:00 EST nhảy lên 03:00 EDT: 02:30 không tồn tại hôm đó
+		{name: "spring forward skips missing time", spec: "30 2 * * *", loc: ny, from: "2024-03-09T12:00:00Z",
+			want: []string{"2024-03-11T06:30:00Z"}},
+		{name: "spring forward hourly", spec: "0 * * * *", loc: ny, from: "2024-03-10T06:30:00Z",
+			want: []string{"2024-03-10T07:00:00Z"}},
+		// 2024-11-03 02:00 EDT lùi về 01:00 EST: 01:xx xuất hiện hai lần
+		{name: "fall back fixed hour runs once", spec: "30 1 * * *", loc: ny, from: "2024-11-03T04:00:00Z",
+			want: []string{"2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"}},
+		{name: "fall back wildcard hour runs twice", spec: "*/30 * * * *", loc: ny, from: "2024-11-03T05:00:00Z",
+			want: []string{"2024-11-03T05:30:00Z", "2024-11-03T06:00:00Z", "2024-11-03T06:30:00Z", "2024-11-03T07:00:00Z"}},
+		{name: "fall back hour step runs twice", spec: "0 */1 * * *", loc: ny, from: "2024-11-03T05:00:00Z",
+			want: []string{"2024-11-03T06:00:00Z", "2024-11-03T07:00:00Z"}},
+		{name: "fall back hour range runs once", spec: "0 0-23 * * *", loc: ny, from: "2024-11-03T05:00:00Z",
+			want: []string{"2024-11-03T07:00:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cur := utc(tt.from)
+			if len(tt.want) == 0 {
+				if next := s.Next(cur); !next.IsZero() {
+					t.Fatalf("Next(%s) = %s, want zero", cur, next)
+				}
+				return
+			}
+			for i, w := range tt.want {
+				next := s.Next(cur)
+				if !next.Equal(utc(w)) {
+					t.Fatalf("run %d: Next(%s) = %s, want %s", i, cur.UTC(), next.UTC(), w)
+				}
+				cur = next
+			}
+		})
+	}
+}
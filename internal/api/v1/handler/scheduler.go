@@ -1,11 +1,12 @@
 package v1handler
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,36 +15,95 @@ import (
 )
 
 // ---------------- Types ----------------
-type apiCallFunc func() (string, int, error)
-
 type APIError struct {
 	Op      string
 	Status  int
 	Message string
+	Err     error // lỗi gốc (thường là *telegram.APIError), dùng cho errors.Is/As
 }
 
 func (e *APIError) Error() string {
 	return fmt.Sprintf("%s failed (status %d): %s", e.Op, e.Status, e.Message)
 }
 
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func newAPIError(op string, status int, err error) *APIError {
+	return &APIError{Op: op, Status: status, Message: err.Error(), Err: err}
+}
+
+// Scheduler là supervisor chạy nhiều job độc lập, mỗi job một goroutine với lịch riêng
 type Scheduler struct {
-	chatID          int64
-	client          *telegram.TelegramClient
-	intervalMinutes float64
-	runCount        int
-	retryCount      int
-	retryDelaySec   int
+	client *telegram.TelegramClient
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	running bool
+	ctx     context.Context // bị huỷ khi Stop, cha của context từng job
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	jobs    map[string]*jobRunner
+	order   []string // thứ tự thêm job, để liệt kê ổn định
+
+	updatesOffset int // update_id lớn nhất đã đọc + 1, để GetUpdates không đọc lại update cũ
+
+	store   JobStore // nil = không lưu, mất hết khi restart
+	catchUp string   // chính sách mặc định cho job không đặt catch_up
+
+	pending      []PendingStep // bước chain đang chờ After, chạy bởi pendingLoop
+	pendingSeq   int
+	pendingWake  chan struct{}
+	closePending context.CancelFunc // dừng pendingLoop, xem Close
+	pendingDone  chan struct{}
+}
+
+// jobRunner giữ job và trạng thái chạy của nó; mọi field được bảo vệ bởi Scheduler.mu
+type jobRunner struct {
+	job      Job
+	schedule Schedule
+	cancel   context.CancelFunc // != nil khi goroutine của job đang chạy
+
+	busy       bool
+	runs       int
+	lastRunAt  time.Time
+	nextRunAt  time.Time // lượt đang chờ, zero khi job không chờ lịch
+	dueAt      time.Time // lượt theo lịch chưa chạy; Stop vẫn giữ để lần Start sau chạy bù
+	lastResult string
+	lastError  string
+}
 
-	mu       sync.Mutex
-	running  bool
-	stopChan chan struct{}
-	wg       sync.WaitGroup
-	logger   *logrus.Logger
+// JobStatus là job kèm trạng thái chạy, dùng cho /scheduler_status và API
+type JobStatus struct {
+	Job
+	Active     bool      `json:"active"` // đang chờ lịch (scheduler chạy, job bật, chưa hết run_limit)
+	Busy       bool      `json:"busy"`   // đang chạy một lượt
+	Runs       int       `json:"runs"`
+	LastRunAt  time.Time `json:"last_run_at,omitempty"`
+	NextRunAt  time.Time `json:"next_run_at,omitempty"`
+	LastResult string    `json:"last_result,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+}
 
-	apiCalls map[string]apiCallFunc
-	apiOrder []string
+// SchedulerStatus là ảnh chụp trạng thái scheduler
+type SchedulerStatus struct {
+	Running bool          `json:"running"`
+	Jobs    []JobStatus   `json:"jobs"`
+	Pending []PendingStep `json:"pending,omitempty"`
 }
 
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobExists   = errors.New("job already exists")
+	// ErrJobBusy: job đang có một lượt chạy khác
+	ErrJobBusy = errors.New("job run already in progress")
+	// errInvalidParam: param của job sai, retry cũng không khắc phục được
+	errInvalidParam = errors.New("invalid param")
+	// errPartialSend: text dài mới gửi được một số phần, các phần đó đã tới chat
+	errPartialSend = errors.New("message partially sent")
+)
+
 // ANSI màu terminal
 const (
 	colorReset = "\033[0m"
@@ -52,24 +112,18 @@ const (
 	colorCyan  = "\033[36m"
 )
 
-// Bảng màu cho từng API
-var apiColors = map[string]string{
-	"SendMessage": "\033[34;1m",
-	"SendGIF":     "\033[35;1m",
-	"SendVoice":   "\033[36;1m",
-	"SendVideo":   "\033[33;1m",
-	"GetUpdates":  "\033[37;1m",
+// Bảng màu cho từng loại action
+var actionColors = map[string]string{
+	ActionSendMessage:   "\033[34;1m",
+	ActionSendAnimation: "\033[35;1m",
+	ActionSendVoice:     "\033[36;1m",
+	ActionSendVideo:     "\033[33;1m",
+	ActionGetUpdates:    "\033[37;1m",
+	ActionChain:         "\033[32;1m",
 }
 
 // ---------------- Constructor ----------------
-func NewScheduler(chatIDStr string, client *telegram.TelegramClient,
-	intervalMinutes float64, runCount, retryCount, retryDelaySec int, logFilePath string) (*Scheduler, error) {
-
-	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid chatID: %v", err)
-	}
-
+func NewScheduler(client *telegram.TelegramClient, logFilePath string) (*Scheduler, error) {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logFile, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -78,35 +132,296 @@ func NewScheduler(chatIDStr string, client *telegram.TelegramClient,
 	}
 	logger.SetOutput(logFile)
 
+	// Job đã có retry riêng (job.Retry) nên tắt retry của client, tránh gửi trùng tin
+	if client != nil {
+		client = client.WithoutRetry()
+	}
 	s := &Scheduler{
-		chatID:          chatID,
-		client:          client,
-		intervalMinutes: intervalMinutes,
-		runCount:        runCount,
-		retryCount:      retryCount,
-		retryDelaySec:   retryDelaySec,
-		stopChan:        make(chan struct{}),
-		logger:          logger,
-		apiCalls:        make(map[string]apiCallFunc),
-	}
-
-	// Đăng ký API calls
-	s.RegisterAPI("SendMessage", s.callSendMessage)
-	s.RegisterAPI("SendGIF", s.callSendGIF)
-	s.RegisterAPI("SendVoice", s.callSendVoice)
-	s.RegisterAPI("SendVideo", s.callSendVideo)
-	s.RegisterAPI("GetUpdates", s.callGetUpdates)
-
-	s.logger.WithFields(logrus.Fields{
-		"chatID": chatID,
-	}).Info("Scheduler initialized")
+		client:      client,
+		logger:      logger,
+		jobs:        make(map[string]*jobRunner),
+		catchUp:     CatchUpSkip,
+		pendingWake: make(chan struct{}, 1),
+		pendingDone: make(chan struct{}),
+	}
+	// Bước chain đang chờ chạy độc lập với Start/Stop
+	var pendingCtx context.Context
+	pendingCtx, s.closePending = context.WithCancel(context.Background())
+	go s.pendingLoop(pendingCtx)
+	s.logger.Info("Scheduler initialized")
 	return s, nil
 }
 
-// ---------------- API registry ----------------
-func (s *Scheduler) RegisterAPI(name string, fn apiCallFunc) {
-	s.apiCalls[name] = fn
-	s.apiOrder = append(s.apiOrder, name)
+// Close dừng scheduler và vòng chạy bước chain đang chờ; các bước chưa chạy vẫn nằm trong store
+func (s *Scheduler) Close() {
+	s.Stop()
+	s.closePending()
+	<-s.pendingDone
+}
+
+// ---------------- Persistence ----------------
+
+// StoreInfo cho biết UseStore đã nạp được gì
+type StoreInfo struct {
+	Found   bool // store đã có dữ liệu (kể cả khi mọi job đã bị xoá); false = lần chạy đầu
+	Jobs    int  // số job đã nạp
+	Running bool // scheduler đang chạy lúc lưu lần cuối, nên Start lại
+}
+
+// UseStore nạp job và trạng thái đã lưu từ store rồi lưu mọi thay đổi về sau vào đó.
+// Chỉ khi info.Found = false mới nên thêm job mặc định. Gọi trước Start.
+func (s *Scheduler) UseStore(store JobStore) (StoreInfo, error) {
+	state, err := store.Load()
+	if err != nil {
+		return StoreInfo{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+	if state == nil {
+		return StoreInfo{}, nil
+	}
+	info := StoreInfo{Found: true, Running: state.Running}
+	for _, stored := range state.Jobs {
+		schedule, err := stored.Job.Validate()
+		if err != nil {
+			s.logTerminal("ERR", fmt.Sprintf("⚠️ Skipping stored job: %v", err))
+			continue
+		}
+		if _, ok := s.jobs[stored.Name]; ok {
+			continue
+		}
+		due := stored.DueAt
+		if due.IsZero() {
+			due = stored.NextRunAt // file lưu trước khi có due_at
+		}
+		s.jobs[stored.Name] = &jobRunner{
+			job:        stored.Job.clone(),
+			schedule:   schedule,
+			runs:       stored.Runs,
+			lastRunAt:  stored.LastRunAt,
+			dueAt:      due,
+			lastResult: stored.LastResult,
+			lastError:  stored.LastError,
+		}
+		s.order = append(s.order, stored.Name)
+		info.Jobs++
+	}
+	s.updatesOffset = state.UpdatesOffset
+	s.pending = state.Pending
+	for i := range s.pending {
+		if s.pending[i].ID == "" { // file lưu trước khi có id
+			s.pending[i].ID = s.newPendingIDLocked()
+		}
+	}
+	s.wakePending()
+	return info, nil
+}
+
+// SetDefaultCatchUp đặt chính sách chạy bù cho job không đặt catch_up (mặc định skip)
+func (s *Scheduler) SetDefaultCatchUp(policy string) error {
+	if err := validateCatchUp(policy); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.catchUp = policy
+	return nil
+}
+
+func (s *Scheduler) catchUpPolicyLocked(job Job) string {
+	if job.CatchUp != "" {
+		return job.CatchUp
+	}
+	return s.catchUp
+}
+
+// persistLocked ghi trạng thái hiện tại vào store; lỗi chỉ được log để không chặn job
+func (s *Scheduler) persistLocked() {
+	if s.store == nil {
+		return
+	}
+	state := &SchedulerState{
+		Jobs:          make([]StoredJob, 0, len(s.order)),
+		Running:       s.running,
+		UpdatesOffset: s.updatesOffset,
+		Pending:       s.pending,
+	}
+	for _, name := range s.order {
+		r := s.jobs[name]
+		state.Jobs = append(state.Jobs, StoredJob{
+			Job:        r.job.clone(),
+			Runs:       r.runs,
+			LastRunAt:  r.lastRunAt,
+			NextRunAt:  r.nextRunAt,
+			DueAt:      r.dueAt,
+			LastResult: r.lastResult,
+			LastError:  r.lastError,
+		})
+	}
+	if err := s.store.Save(state); err != nil {
+		s.logger.WithError(err).Error("cannot persist scheduler state")
+		fmt.Printf("%s⚠️ cannot persist scheduler state: %v%s\n", colorRed, err, colorReset)
+	}
+}
+
+// ---------------- Job registry ----------------
+
+// AddJob thêm job; nếu scheduler đang chạy và job bật thì job bắt đầu chờ lịch ngay
+func (s *Scheduler) AddJob(job Job) error {
+	schedule, err := job.Validate()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+	r := &jobRunner{job: job.clone(), schedule: schedule}
+	s.jobs[job.Name] = r
+	s.order = append(s.order, job.Name)
+	if s.running && r.startable() {
+		s.startJobLocked(r)
+	}
+	s.persistLocked()
+	return nil
+}
+
+// UpdateJob thay định nghĩa job name bằng job (giữ số lượt đã chạy); job đang chờ lịch được
+// khởi động lại với lịch mới, lượt đang chạy dở vẫn chạy hết với định nghĩa cũ
+func (s *Scheduler) UpdateJob(name string, job Job) error {
+	job.Name = name
+	schedule, err := job.Validate()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	r.stopLocked()
+	r.job, r.schedule = job.clone(), schedule
+	if s.running && r.startable() {
+		s.startJobLocked(r)
+	}
+	s.persistLocked()
+	return nil
+}
+
+// RemoveJob dừng và xoá job
+func (s *Scheduler) RemoveJob(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	r.stopLocked()
+	delete(s.jobs, name)
+	for i, n := range s.order {
+		if n == name {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	s.persistLocked()
+	return nil
+}
+
+// SetJobEnabled bật/tắt job; job đang chờ lịch bị dừng ngay, lượt đang chạy dở vẫn chạy hết
+func (s *Scheduler) SetJobEnabled(name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	r.job.Enabled = enabled
+	switch {
+	case !enabled:
+		r.stopLocked()
+	case s.running && r.cancel == nil && r.startable():
+		s.startJobLocked(r)
+	}
+	s.persistLocked()
+	return nil
+}
+
+// DisableJobsWithAction tắt mọi job có action này (vd get_updates khi đã có Poller đọc update)
+func (s *Scheduler) DisableJobsWithAction(action string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, name := range s.order {
+		r := s.jobs[name]
+		if r.job.Action == action && r.job.Enabled {
+			r.job.Enabled = false
+			r.stopLocked()
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		s.persistLocked()
+	}
+	return names
+}
+
+// Jobs trả về mọi job kèm trạng thái, theo thứ tự thêm vào
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]JobStatus, 0, len(s.order))
+	for _, name := range s.order {
+		jobs = append(jobs, s.jobs[name].statusLocked())
+	}
+	return jobs
+}
+
+// Job trả về một job theo tên
+func (s *Scheduler) Job(name string) (JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.jobs[name]
+	if !ok {
+		return JobStatus{}, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return r.statusLocked(), nil
+}
+
+func (r *jobRunner) statusLocked() JobStatus {
+	return JobStatus{
+		Job:        r.job.clone(),
+		Active:     r.cancel != nil,
+		Busy:       r.busy,
+		Runs:       r.runs,
+		LastRunAt:  r.lastRunAt,
+		NextRunAt:  r.nextRunAt,
+		LastResult: r.lastResult,
+		LastError:  r.lastError,
+	}
+}
+
+// startable: job bật và chưa chạy đủ run_limit
+func (r *jobRunner) startable() bool {
+	return r.job.Enabled && (r.job.RunLimit == 0 || r.runs < r.job.RunLimit)
+}
+
+// stopLocked dừng việc chờ lịch và bỏ luôn lượt đang chờ (job bị tắt/sửa/xoá/hết lượt)
+func (r *jobRunner) stopLocked() {
+	r.haltLocked()
+	r.dueAt = time.Time{}
+}
+
+// haltLocked dừng việc chờ lịch nhưng giữ dueAt, để Start lần sau chạy bù theo catch_up
+func (r *jobRunner) haltLocked() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.nextRunAt = time.Time{}
 }
 
 // ---------------- Helper ----------------
@@ -128,40 +443,83 @@ func (s *Scheduler) logTerminal(level, msg string) {
 	}
 	fmt.Printf("%s %s %s%s%s\n", ts, level, msg, color, colorReset)
 	s.logger.WithFields(logrus.Fields{
-		"chatID": s.chatID,
-		"level":  level,
+		"level": level,
 	}).Info(msg)
 }
 
 // ---------------- Retry ----------------
-func (s *Scheduler) callWithRetry(apiName string, fn apiCallFunc) (string, int, error) {
+
+// callWithRetry chạy action cho một chat, thử lại theo job.Retry; trả về cả chat_id
+// (đã đổi nếu group được nâng lên supergroup trong lúc gửi)
+func (s *Scheduler) callWithRetry(ctx context.Context, r *jobRunner, job Job, chatID int64, params map[string]string, fn actionFunc) (actionResult, int64, error) {
 	var lastErr error
-	var status int
-	for attempt := 0; attempt <= s.retryCount; attempt++ {
-		select {
-		case <-s.stopChan:
-			return "", 0, fmt.Errorf("stopped")
-		default:
+	var res actionResult
+	migrated := false
+	for attempt := 0; attempt <= job.Retry.Attempts; attempt++ {
+		if ctx.Err() != nil {
+			return actionResult{}, chatID, fmt.Errorf("stopped")
 		}
 
-		res, st, err := fn()
-		status = st
+		var err error
+		res, err = fn(ctx, s, chatID, params)
 		if err == nil {
-			return res, status, nil
+			return res, chatID, nil
 		}
 
 		lastErr = err
-		s.logTerminal("ERR", fmt.Sprintf("⚠️ %s attempt %d/%d failed: %v", apiName, attempt+1, s.retryCount+1, err))
+		s.logTerminal("ERR", fmt.Sprintf("⚠️ %s attempt %d/%d failed: %v", job.Name, attempt+1, job.Retry.Attempts+1, err))
+
+		// Group đã lên supergroup: đổi sang chat_id mới rồi gửi lại ngay,
+		// không tính là một lần thử (chỉ một lần mỗi lượt gọi)
+		var tgErr *telegram.APIError
+		if !migrated && errors.As(err, &tgErr) && tgErr.MigrateToChatID() != 0 {
+			newID := tgErr.MigrateToChatID()
+			s.logTerminal("INF", fmt.Sprintf("🔀 Chat %d migrated to %d", chatID, newID))
+			s.migrateChat(r, chatID, newID)
+			chatID = newID
+			migrated = true
+			attempt--
+			continue
+		}
+		// Lỗi không thể tự khắc phục thì không retry; gửi thiếu phần cũng vậy vì gửi lại sẽ lặp các phần đã tới chat
+		if errors.Is(err, errInvalidParam) || errors.Is(err, errPartialSend) || errors.Is(err, telegram.ErrForbidden) || errors.Is(err, telegram.ErrBadRequest) || errors.Is(err, telegram.ErrUnauthorized) {
+			return res, chatID, err
+		}
+		if attempt == job.Retry.Attempts {
+			break
+		}
 
-		// Exponential backoff
-		delay := time.Duration(float64(s.retryDelaySec)*math.Pow(2, float64(attempt))) * time.Second
+		// Exponential backoff, nhưng tôn trọng retry_after nếu bị flood wait
+		delay := time.Duration(float64(job.Retry.DelaySec)*math.Pow(2, float64(attempt))) * time.Second
+		if tgErr != nil && tgErr.RetryAfter() > delay {
+			delay = tgErr.RetryAfter()
+		}
 		select {
 		case <-time.After(delay):
-		case <-s.stopChan:
-			return "", status, fmt.Errorf("stopped during retry")
+		case <-ctx.Done():
+			return res, chatID, fmt.Errorf("stopped during retry")
+		}
+	}
+	return res, chatID, lastErr
+}
+
+// migrateChat thay chat_id cũ bằng chat_id mới trong job để các lượt sau gửi đúng chỗ;
+// r nil (bước chain của job đã bị xoá) thì không có gì để sửa
+func (s *Scheduler) migrateChat(r *jobRunner, oldID, newID int64) {
+	if r == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, id := range r.job.ChatIDs {
+		if id == oldID {
+			r.job.ChatIDs[i] = newID
 		}
 	}
-	return "", status, lastErr
+	if r.job.ReportChatID == oldID {
+		r.job.ReportChatID = newID
+	}
+	s.persistLocked()
 }
 
 // ---------------- Start / Stop ----------------
@@ -172,13 +530,27 @@ func (s *Scheduler) Start() error {
 		return fmt.Errorf("scheduler already running")
 	}
 	s.running = true
-	s.stopChan = make(chan struct{})
-	s.wg.Add(1)
-	s.logTerminal("INF", "🚀 Scheduler started")
-	go s.runLoop()
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	// Chain bị lần Stop trước cắt ngang được chạy tiếp (vẫn theo due_at)
+	for i := range s.pending {
+		s.pending[i].AwaitStart = false
+	}
+	s.wakePending()
+	started := 0
+	for _, name := range s.order {
+		if r := s.jobs[name]; r.startable() {
+			s.startJobLocked(r)
+			started++
+		}
+	}
+	s.persistLocked()
+	s.logTerminal("INF", fmt.Sprintf("🚀 Scheduler started (%d job(s))", started))
 	return nil
 }
 
+// Stop dừng mọi job và chờ các lượt đang chạy kết thúc (request đang gửi bị huỷ qua context).
+// Lượt đang chờ vẫn được lưu để Start sau chạy bù; trạng thái tắt được lưu nên restart không tự Start.
+// Chain đang chạy dừng lại, các bước còn lại chờ tới lần Start sau (xem PendingStep.AwaitStart).
 func (s *Scheduler) Stop() {
 	s.mu.Lock()
 	if !s.running {
@@ -186,7 +558,11 @@ func (s *Scheduler) Stop() {
 		return
 	}
 	s.running = false
-	close(s.stopChan)
+	s.cancel()
+	for _, r := range s.jobs {
+		r.haltLocked()
+	}
+	s.persistLocked()
 	s.mu.Unlock()
 
 	s.logTerminal("INF", "🛑 Scheduler stopping...")
@@ -200,144 +576,227 @@ func (s *Scheduler) IsRunning() bool {
 	return s.running
 }
 
-// ---------------- runLoop ----------------
-func (s *Scheduler) runLoop() {
-	defer s.wg.Done()
-	count := 0
+// Status trả về trạng thái scheduler và từng job
+func (s *Scheduler) Status() SchedulerStatus {
+	return SchedulerStatus{Running: s.IsRunning(), Jobs: s.Jobs(), Pending: s.PendingSteps()}
+}
 
-	s.runOnce()
-	count++
+// RunJobNow chạy một lượt của job ngay trong nền, không ảnh hưởng lịch của job.
+// Chạy được cả khi scheduler đang tắt hoặc job bị tắt.
+func (s *Scheduler) RunJobNow(name string) error {
+	s.mu.Lock()
+	r, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if r.busy {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobBusy, name)
+	}
+	// Scheduler đang chạy thì lượt chạy tay cũng bị Stop huỷ và chờ
+	ctx, tracked := context.Background(), s.running
+	if tracked {
+		ctx = s.ctx
+		s.wg.Add(1)
+	}
+	s.mu.Unlock()
 
-	interval := time.Duration(s.intervalMinutes * float64(time.Minute))
-	timer := time.NewTimer(interval)
-	defer timer.Stop()
+	s.logTerminal("INF", fmt.Sprintf("▶️ Manual run requested: %s", name))
+	go func() {
+		if tracked {
+			defer s.wg.Done()
+		}
+		_ = s.runJob(ctx, r)
+	}()
+	return nil
+}
 
-	for {
-		select {
-		case <-s.stopChan:
-			s.logTerminal("INF", "⏹ Received stop signal, exiting runLoop")
-			return
-		case <-timer.C:
-			s.runOnce()
-			count++
-			if s.runCount > 0 && count >= s.runCount {
-				s.logTerminal("INF", fmt.Sprintf("🏁 Reached runCount limit %d, stopping loop", s.runCount))
-				s.Stop()
-				return
-			}
-			timer.Reset(interval)
+// RunNow chạy ngay một lượt mọi job đang bật; trả về tên các job đã được chạy.
+// Job đang bận bị bỏ qua.
+func (s *Scheduler) RunNow() []string {
+	var started []string
+	for _, job := range s.Jobs() {
+		if !job.Enabled {
+			continue
+		}
+		if err := s.RunJobNow(job.Name); err == nil {
+			started = append(started, job.Name)
 		}
 	}
+	return started
 }
 
-// ---------------- runOnce ----------------
-func (s *Scheduler) runOnce() {
-	now := time.Now().Format("15:04:05")
-	fmt.Printf("%s╔════════════════════════════════════════════════════════════════╗%s\n", colorCyan, colorReset)
-	fmt.Printf("%s║ 🚀 Scheduler run started at %s ║%s\n", colorCyan, now, colorReset)
-	fmt.Printf("%s╚════════════════════════════════════════════════════════════════╝%s\n", colorCyan, colorReset)
+// ---------------- Job loop ----------------
 
-	fmt.Printf("%-4s | %-12s | %-40s\n", "No.", "Type", "Message / Status")
-	fmt.Printf("%s---------------------------------------------------------------%s\n", colorCyan, colorReset)
+func (s *Scheduler) startJobLocked(r *jobRunner) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	r.cancel = cancel
+	s.wg.Add(1)
+	go s.jobLoop(ctx, s.ctx, r)
+}
 
-	for i, apiName := range s.apiOrder {
-		typeColor := apiColors[apiName]
-		res, status, err := s.callWithRetry(apiName, s.apiCalls[apiName])
+// jobLoop chờ lịch và chạy job cho tới khi ctx bị huỷ hoặc hết run_limit. Mỗi lượt chạy với
+// runCtx (context của scheduler) nên tắt/sửa job chỉ dừng việc chờ, lượt đang chạy dở vẫn chạy hết;
+// chỉ Stop mới huỷ lượt đang chạy.
+func (s *Scheduler) jobLoop(ctx, runCtx context.Context, r *jobRunner) {
+	defer s.wg.Done()
+	s.mu.Lock()
+	job, schedule := r.job, r.schedule
+	// Lượt đang chờ từ trước (process tắt hoặc Stop khi job đang chờ) đã qua: có lượt bị lỡ
+	missed := missedRuns(schedule, r.dueAt, time.Now(), s.catchUpPolicyLocked(job))
+	s.mu.Unlock()
 
-		var msg, statusColor string
-		if err != nil {
-			msg = fmt.Sprintf("%v (status=%d)", err, status)
-			statusColor = colorRed
-		} else {
-			msg = fmt.Sprintf("%s (status=%d)", res, status)
-			statusColor = colorGreen
+	if missed > 0 {
+		s.logTerminal("INF", fmt.Sprintf("⏪ %s: catching up %d missed run(s)", job.Name, missed))
+		for i := 0; i < missed && ctx.Err() == nil && s.jobStartable(r); i++ {
+			_ = s.runJob(runCtx, r)
+		}
+	} else if job.RunOnStart {
+		_ = s.runJob(runCtx, r)
+	}
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if !s.jobStartable(r) {
+			s.logTerminal("INF", fmt.Sprintf("🏁 %s reached run limit %d", job.Name, job.RunLimit))
+			s.finishJob(ctx, r)
+			return
 		}
 
-		fmt.Printf("%-4d | %s%-12s%s | %s%-40s%s\n", i, typeColor, apiName, colorReset, statusColor, msg, colorReset)
-		s.logger.WithFields(logrus.Fields{
-			"chatID":    s.chatID,
-			"apiName":   apiName,
-			"runCount":  i,
-			"timestamp": time.Now().Format(time.RFC3339),
-		}).Info(msg)
-
-		select {
-		case <-s.stopChan:
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			s.logTerminal("ERR", fmt.Sprintf("❌ %s: schedule %q has no upcoming run", job.Name, schedule))
+			s.finishJob(ctx, r)
+			return
+		}
+		s.mu.Lock()
+		r.nextRunAt, r.dueAt = next, next
+		s.persistLocked()
+		s.mu.Unlock()
+		if !sleepUntil(ctx, next) {
 			return
-		default:
-			_, _, _ = s.client.SendMessageRaw(s.chatID, fmt.Sprintf("%s: %s", apiName, msg))
 		}
+		_ = s.runJob(runCtx, r)
 	}
-
-	fmt.Printf("%s╔════════════════════════════════════════════════════════════════╗%s\n", colorCyan, colorReset)
-	fmt.Printf("%s║ ✔ Scheduler run completed END ║%s\n", colorCyan, colorReset)
-	fmt.Printf("%s╚════════════════════════════════════════════════════════════════╝%s\n", colorCyan, colorReset)
 }
 
-// ---------------- API calls ----------------
-func (s *Scheduler) callSendMessage() (string, int, error) {
-	text := "🚀 Scheduler: Test message sent at " + time.Now().Format(time.RFC3339)
-	_, status, err := s.client.SendMessageRaw(s.chatID, text)
-	if err != nil {
-		return "", status, &APIError{"SendMessage", status, err.Error()}
+// finishJob đánh dấu job không còn chờ lịch, trừ khi ctx đã bị huỷ (khi đó người huỷ đã làm việc này)
+func (s *Scheduler) finishJob(ctx context.Context, r *jobRunner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ctx.Err() == nil {
+		r.stopLocked()
+		s.persistLocked()
 	}
-	return "SendMessage ok", status, nil
 }
 
-func (s *Scheduler) callSendGIF() (string, int, error) {
-	filePath := "./uploads/happy.gif"
-	if !checkFileExists(filePath) {
-		return "skip sendAnimation - file not found", 0, nil
-	}
-	_, status, err := s.client.SendAnimationRaw(s.chatID, filePath, "🎉 Scheduler test GIF")
-	if err != nil {
-		return "", status, &APIError{"SendGIF", status, err.Error()}
-	}
-	return fmt.Sprintf("sendAnimation sent: %s", filePath), status, nil
+func (s *Scheduler) jobStartable(r *jobRunner) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return r.startable()
 }
 
-func (s *Scheduler) callSendVoice() (string, int, error) {
-	filePath := "./uploads/test.ogg"
-	if !checkFileExists(filePath) {
-		return "skip sendVoice - file not found", 0, nil
-	}
-	_, status, err := s.client.SendVoiceRaw(s.chatID, filePath, "🎙 Scheduler test voice")
-	if err != nil {
-		return "", status, &APIError{"SendVoice", status, err.Error()}
+// sleepUntil chờ tới next (theo đồng hồ thật, kiểm tra lại mỗi phút để không lệch khi máy sleep
+// hay chỉnh giờ); trả về false nếu ctx bị huỷ
+func sleepUntil(ctx context.Context, next time.Time) bool {
+	for {
+		wait := time.Until(next)
+		if wait <= 0 {
+			return true
+		}
+		if wait > time.Minute {
+			wait = time.Minute
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
 	}
-	return fmt.Sprintf("sendVoice sent: %s", filePath), status, nil
 }
 
-func (s *Scheduler) callSendVideo() (string, int, error) {
-	filePath := "./uploads/test_small.mp4"
-	if !checkFileExists(filePath) {
-		return "skip sendVideo - file not found", 0, nil
-	}
-	_, status, err := s.client.SendVideoRaw(s.chatID, filePath, "🎥 Scheduler test video")
-	if err != nil {
-		return "", status, &APIError{"SendVideo", status, err.Error()}
-	}
-	return fmt.Sprintf("sendVideo sent: %s", filePath), status, nil
-}
+// ---------------- runJob ----------------
 
-func (s *Scheduler) callGetUpdates() (string, int, error) {
-	params := "?offset=0&limit=100&timeout=0"
-	body, status, err := s.client.FetchUpdatesRaw(params)
-	if err != nil {
-		return "", status, &APIError{"GetUpdates", status, err.Error()}
+// runJob chạy một lượt job tới từng chat; hai lượt của cùng job không chạy chồng nhau
+func (s *Scheduler) runJob(ctx context.Context, r *jobRunner) error {
+	s.mu.Lock()
+	if r.busy {
+		s.mu.Unlock()
+		s.logTerminal("INF", fmt.Sprintf("⏭ %s: previous run still in progress, skipping", r.job.Name))
+		return ErrJobBusy
 	}
+	r.busy = true
+	job := r.job.clone()
+	s.mu.Unlock()
 
-	var result struct {
-		Ok     bool              `json:"ok"`
-		Result []telegram.Update `json:"result"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", status, &APIError{"GetUpdates", status, err.Error()}
+	action := jobActions[job.Action]
+	chats := job.ChatIDs
+	if !action.needsChat {
+		chats = []int64{0}
 	}
-	if !result.Ok {
-		return "", status, &APIError{"GetUpdates", status, "telegram API returned not ok"}
+
+	var results, errs []string
+	for _, chatID := range chats {
+		if ctx.Err() != nil {
+			errs = append(errs, "stopped")
+			break
+		}
+		var msg string
+		var err error
+		if job.Action == ActionChain {
+			var res string
+			var cont *PendingStep
+			res, cont, err = s.runChain(ctx, r, chainStart(job, chatID))
+			if cont != nil {
+				// cont kèm lỗi: chain bị Stop cắt ngang, chờ Start lại mới chạy tiếp
+				cont.AwaitStart = err != nil
+				s.enqueuePending(*cont)
+			}
+			msg = chainMessage(res, err)
+		} else {
+			var res actionResult
+			res, _, err = s.callWithRetry(ctx, r, job, chatID, job.Params, action.run)
+			if err != nil {
+				msg = fmt.Sprintf("%v (status=%d)", err, res.Status)
+			} else {
+				msg = fmt.Sprintf("%s (status=%d)", res.Text, res.Status)
+			}
+		}
+		statusColor := colorGreen
+		if err != nil {
+			statusColor = colorRed
+			errs = append(errs, msg)
+		} else {
+			results = append(results, msg)
+		}
+
+		fmt.Printf("%s%-16s%s | %-14d | %s%s%s\n", actionColors[job.Action], job.Name, colorReset, chatID, statusColor, msg, colorReset)
+		s.logger.WithFields(logrus.Fields{
+			"job":       job.Name,
+			"action":    job.Action,
+			"chatID":    chatID,
+			"timestamp": time.Now().Format(time.RFC3339),
+		}).Info(msg)
+
+		if job.ReportChatID != 0 && ctx.Err() == nil {
+			_, _, _ = s.client.SendMessageRawContext(ctx, job.ReportChatID, fmt.Sprintf("%s: %s", job.Name, msg))
+		}
 	}
 
-	count := len(result.Result)
-	return fmt.Sprintf("GetUpdates ok: %d new", count), status, nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.busy = false
+	r.runs++
+	r.lastRunAt = time.Now()
+	r.lastResult = strings.Join(results, "; ")
+	r.lastError = strings.Join(errs, "; ")
+	s.persistLocked()
+	if len(errs) > 0 {
+		return errors.New(r.lastError)
+	}
+	return nil
 }
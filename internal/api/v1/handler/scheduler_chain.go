@@ -0,0 +1,321 @@
+package v1handler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JobStep là một bước của job chain. Params có thể tham chiếu kết quả bước trước bằng
+// {{N.message_id}} / {{N.message_ids}} / {{N.invite_link}} (N tính từ 1) hoặc {{prev.message_id}}.
+// After là thời gian chờ sau bước trước (vd "6h" để tự xoá message sau 6 tiếng).
+type JobStep struct {
+	Action string            `json:"action"`
+	Params map[string]string `json:"params,omitempty"`
+	After  string            `json:"after,omitempty"`
+}
+
+// PendingStep là phần còn lại của một chain đang chờ After của bước Next; được lưu vào store
+// để bước chờ lâu (auto-delete) vẫn chạy sau khi restart. Bước chỉ bị xoá khỏi store sau khi
+// chạy xong, nên process chết giữa chừng thì lần sau bước đó được chạy lại.
+// Chain bị Stop cắt ngang có AwaitStart, chỉ chạy tiếp sau lần Start kế tiếp.
+type PendingStep struct {
+	ID           string              `json:"id"`
+	Job          string              `json:"job"`
+	ChatID       int64               `json:"chat_id"`
+	Steps        []JobStep           `json:"steps"`
+	Next         int                 `json:"next"` // index (từ 0) của bước sẽ chạy
+	Vars         []map[string]string `json:"vars"` // kết quả các bước đã chạy
+	Retry        JobRetry            `json:"retry"`
+	ReportChatID int64               `json:"report_chat_id,omitempty"`
+	DueAt        time.Time           `json:"due_at"`
+	AwaitStart   bool                `json:"await_start,omitempty"`
+}
+
+var stepRefRe = regexp.MustCompile(`\{\{\s*(prev|\d+)\.([a-z_]+)\s*\}\}`)
+
+// validateSteps kiểm tra các bước của chain: action hợp lệ, đủ params, After đúng định dạng
+// và chỉ tham chiếu tới bước đứng trước
+func validateSteps(steps []JobStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("action %s needs at least one step", ActionChain)
+	}
+	for i, step := range steps {
+		action, ok := jobActions[step.Action]
+		if !ok || step.Action == ActionChain || !action.needsChat {
+			return fmt.Errorf("step %d: action %q cannot be used in a chain", i+1, step.Action)
+		}
+		if err := validateParams(step.Action, step.Params, i); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+		if step.After == "" {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("step 1: after is not allowed on the first step")
+		}
+		if d, err := time.ParseDuration(step.After); err != nil || d <= 0 {
+			return fmt.Errorf("step %d: invalid after %q (vd 30m, 6h)", i+1, step.After)
+		}
+	}
+	return nil
+}
+
+// validateParams kiểm tra params bắt buộc của action và tham chiếu {{N.x}};
+// done là số bước đã chạy trước đó (0 với job thường)
+func validateParams(action string, params map[string]string, done int) error {
+	for _, p := range jobActions[action].required {
+		if params[p] == "" {
+			return fmt.Errorf("action %s needs param %q", action, p)
+		}
+	}
+	for _, v := range params {
+		for _, m := range stepRefRe.FindAllStringSubmatch(v, -1) {
+			if m[1] == "prev" {
+				if done == 0 {
+					return fmt.Errorf("%s has no previous step", m[0])
+				}
+				continue
+			}
+			if n, _ := strconv.Atoi(m[1]); n < 1 || n > done {
+				return fmt.Errorf("%s must refer to an earlier step", m[0])
+			}
+		}
+	}
+	return nil
+}
+
+// resolveParams thay {{N.x}} bằng kết quả của bước đã chạy
+func resolveParams(params map[string]string, vars []map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(params))
+	for k, v := range params {
+		var missing string
+		resolved[k] = stepRefRe.ReplaceAllStringFunc(v, func(ref string) string {
+			m := stepRefRe.FindStringSubmatch(ref)
+			idx := len(vars) - 1
+			if m[1] != "prev" {
+				n, _ := strconv.Atoi(m[1])
+				idx = n - 1
+			}
+			if idx < 0 || idx >= len(vars) || vars[idx][m[2]] == "" {
+				missing = ref
+				return ""
+			}
+			return vars[idx][m[2]]
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("param %s: %s has no value", k, missing)
+		}
+	}
+	return resolved, nil
+}
+
+// runChain chạy các bước từ start cho một chat. Gặp bước có After thì trả về ngay kèm phần còn
+// lại (cont) để đưa vào hàng chờ. ctx bị huỷ trước một bước thì cont bắt đầu lại từ bước đó;
+// huỷ trong lúc chạy thì bước đó có thể đã tới Telegram nên không chạy lại, cont bắt đầu từ
+// bước sau (vẫn chờ After của nó). Khi đó err != nil cùng cont. cont nil khi chain đã chạy hết hoặc gặp lỗi.
+func (s *Scheduler) runChain(ctx context.Context, r *jobRunner, p PendingStep) (string, *PendingStep, error) {
+	job := Job{Name: p.Job, Retry: p.Retry}
+	p.Vars = append([]map[string]string(nil), p.Vars...)
+	var done []string
+	for i := p.Next; i < len(p.Steps); i++ {
+		step := p.Steps[i]
+		if i > p.Next && step.After != "" {
+			d, _ := time.ParseDuration(step.After)
+			p.Next, p.DueAt = i, time.Now().Add(d)
+			done = append(done, fmt.Sprintf("%d:%s scheduled at %s", i+1, step.Action, p.DueAt.Format("2006-01-02 15:04:05")))
+			return strings.Join(done, " → "), &p, nil
+		}
+		if ctx.Err() != nil {
+			p.Next, p.DueAt = i, time.Now()
+			return strings.Join(done, " → "), &p, fmt.Errorf("stopped before step %d, queued to resume", i+1)
+		}
+
+		params, err := resolveParams(step.Params, p.Vars)
+		if err != nil {
+			return strings.Join(done, " → "), nil, fmt.Errorf("step %d %s: %v", i+1, step.Action, err)
+		}
+		res, chatID, err := s.callWithRetry(ctx, r, job, p.ChatID, params, jobActions[step.Action].run)
+		p.ChatID = chatID
+		if err != nil && ctx.Err() != nil {
+			p.Vars = append(p.Vars, res.Vars)
+			if i+1 == len(p.Steps) {
+				return strings.Join(done, " → "), nil, fmt.Errorf("stopped during step %d, not replayed", i+1)
+			}
+			p.Next, p.DueAt = i+1, time.Now()
+			if after := p.Steps[i+1].After; after != "" {
+				d, _ := time.ParseDuration(after)
+				p.DueAt = p.DueAt.Add(d)
+			}
+			return strings.Join(done, " → "), &p, fmt.Errorf("stopped during step %d, not replayed, queued to resume from step %d", i+1, i+2)
+		}
+		if err != nil {
+			return strings.Join(done, " → "), nil, fmt.Errorf("step %d %s: %v (status=%d)", i+1, step.Action, err, res.Status)
+		}
+		p.Vars = append(p.Vars, res.Vars)
+		done = append(done, fmt.Sprintf("%d:%s", i+1, res.Text))
+	}
+	return strings.Join(done, " → "), nil, nil
+}
+
+// chainMessage nối các bước đã chạy với lỗi (nếu có) thành một dòng kết quả
+func chainMessage(done string, err error) string {
+	if err == nil {
+		return done
+	}
+	if done == "" {
+		return err.Error()
+	}
+	return done + " → " + err.Error()
+}
+
+// chainStart là PendingStep cho lượt chạy mới của job chain tới một chat
+func chainStart(job Job, chatID int64) PendingStep {
+	return PendingStep{
+		Job:          job.Name,
+		ChatID:       chatID,
+		Steps:        job.Steps,
+		Retry:        job.Retry,
+		ReportChatID: job.ReportChatID,
+	}
+}
+
+// ---------------- Pending steps ----------------
+
+func (s *Scheduler) enqueuePending(p PendingStep) {
+	s.mu.Lock()
+	s.addPendingLocked(p)
+	s.persistLocked()
+	s.mu.Unlock()
+	s.wakePending()
+}
+
+// finishPending xoá bước id đã chạy xong khỏi hàng chờ và thêm phần còn lại cont (nếu có)
+// trong cùng một lần lưu
+func (s *Scheduler) finishPending(id string, cont *PendingStep) {
+	s.mu.Lock()
+	for i, p := range s.pending {
+		if p.ID == id {
+			s.pending = append(s.pending[:i:i], s.pending[i+1:]...)
+			break
+		}
+	}
+	if cont != nil {
+		s.addPendingLocked(*cont)
+	}
+	s.persistLocked()
+	s.mu.Unlock()
+	s.wakePending()
+}
+
+func (s *Scheduler) addPendingLocked(p PendingStep) {
+	p.ID = s.newPendingIDLocked()
+	s.pending = append(s.pending, p)
+}
+
+func (s *Scheduler) newPendingIDLocked() string {
+	s.pendingSeq++
+	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), s.pendingSeq)
+}
+
+func (s *Scheduler) wakePending() {
+	select {
+	case s.pendingWake <- struct{}{}:
+	default:
+	}
+}
+
+// PendingSteps trả về các bước chain đang chờ, sớm nhất trước
+func (s *Scheduler) PendingSteps() []PendingStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := append([]PendingStep(nil), s.pending...)
+	sort.Slice(pending, func(i, j int) bool { return pending[i].DueAt.Before(pending[j].DueAt) })
+	return pending
+}
+
+// duePending trả về (không xoá) các bước đã tới giờ và thời điểm của bước chờ sớm nhất còn lại;
+// bước AwaitStart bị bỏ qua tới khi Start
+func (s *Scheduler) duePending(now time.Time) ([]PendingStep, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []PendingStep
+	var next time.Time
+	for _, p := range s.pending {
+		if p.AwaitStart {
+			continue
+		}
+		if !p.DueAt.After(now) {
+			due = append(due, p)
+			continue
+		}
+		if next.IsZero() || p.DueAt.Before(next) {
+			next = p.DueAt
+		}
+	}
+	return due, next
+}
+
+// pendingLoop chạy các bước chain tới giờ suốt vòng đời của Scheduler (tới khi Close),
+// kể cả khi scheduler đang Stop (trừ chain bị chính Stop cắt ngang, xem AwaitStart);
+// bước tới giờ lúc process tắt được chạy bù ngay khi khởi động.
+func (s *Scheduler) pendingLoop(ctx context.Context) {
+	defer close(s.pendingDone)
+	for {
+		// Bước bị huỷ giữa chừng quay lại hàng chờ với due_at = now, phải thoát trước khi lấy lại
+		if ctx.Err() != nil {
+			return
+		}
+		due, next := s.duePending(time.Now())
+		for _, p := range due {
+			s.runPending(ctx, p)
+		}
+		if len(due) > 0 {
+			continue
+		}
+		// Kiểm tra lại ít nhất mỗi phút như sleepUntil, hoặc ngay khi hàng chờ thay đổi
+		wait := time.Minute
+		if !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.pendingWake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Scheduler) runPending(ctx context.Context, p PendingStep) {
+	s.mu.Lock()
+	r := s.jobs[p.Job] // nil nếu job đã bị xoá, các bước còn lại vẫn chạy
+	s.mu.Unlock()
+
+	res, cont, err := s.runChain(ctx, r, p)
+	s.finishPending(p.ID, cont)
+	msg, statusColor := chainMessage(res, err), colorGreen
+	if err != nil {
+		statusColor = colorRed
+	}
+	fmt.Printf("%s%-16s%s | %-14d | %s%s%s\n", actionColors[ActionChain], p.Job, colorReset, p.ChatID, statusColor, msg, colorReset)
+	s.logger.WithFields(logrus.Fields{
+		"job":       p.Job,
+		"action":    ActionChain,
+		"chatID":    p.ChatID,
+		"step":      p.Next + 1,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}).Info(msg)
+	if p.ReportChatID != 0 && ctx.Err() == nil {
+		_, _, _ = s.client.SendMessageRawContext(ctx, p.ReportChatID, fmt.Sprintf("%s: %s", p.Job, msg))
+	}
+}
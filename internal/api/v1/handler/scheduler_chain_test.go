@@ -0,0 +1,167 @@
+package v1handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dnk.com/hoc-golang/telegram"
+)
+
+func TestPendingStepRunsWhileStoppedAndIsRemovedAfterSuccess(t *testing.T) {
+	release := make(chan struct{})
+	calls := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- r.URL.Path
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+
+	store := NewFileJobStore(filepath.Join(t.TempDir(), "jobs.json"))
+	// Bước auto-delete đã tới giờ, lưu từ bản cũ (chưa có id)
+	if err := store.Save(&SchedulerState{Pending: []PendingStep{{
+		Job:    "announce",
+		ChatID: -100,
+		Steps: []JobStep{
+			{Action: ActionSendMessage, Params: map[string]string{"text": "hi"}},
+			{Action: ActionDeleteMessage, Params: map[string]string{"message_id": "{{1.message_id}}"}, After: "1h"},
+		},
+		Next:  1,
+		Vars:  []map[string]string{{"message_id": "42"}},
+		DueAt: time.Now().Add(-time.Minute),
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := telegram.NewTelegramClient("TOKEN", telegram.WithBaseURL(srv.URL), telegram.WithoutRateLimit())
+	s, err := NewScheduler(client, filepath.Join(t.TempDir(), "scheduler.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.UseStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	// Scheduler chưa Start nhưng bước chờ vẫn chạy
+	select {
+	case path := <-calls:
+		if !strings.HasSuffix(path, "/deleteMessage") {
+			t.Fatalf("called %s, want deleteMessage", path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("pending step did not run while scheduler is stopped")
+	}
+
+	// Đang chạy: bước vẫn nằm trong store để chạy lại nếu process chết lúc này
+	state, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Pending) != 1 {
+		t.Fatalf("pending in store while running = %d, want 1", len(state.Pending))
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(s.PendingSteps()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("pending step not removed after success")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if state, _ = store.Load(); len(state.Pending) != 0 {
+		t.Fatalf("pending in store after success = %d, want 0", len(state.Pending))
+	}
+}
+
+func TestChainInterruptedByStopWaitsForStart(t *testing.T) {
+	var mu sync.Mutex
+	texts := map[string]int{}
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		text, _ := p["text"].(string)
+		mu.Lock()
+		texts[text]++
+		mu.Unlock()
+		if text == "first" {
+			// Giữ request tới khi Stop huỷ, như khi Telegram đã nhận nhưng chưa kịp trả lời
+			started <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":9}}`))
+	}))
+	defer srv.Close()
+	count := func(text string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return texts[text]
+	}
+
+	client := telegram.NewTelegramClient("TOKEN", telegram.WithBaseURL(srv.URL), telegram.WithoutRateLimit())
+	s, err := NewScheduler(client, filepath.Join(t.TempDir(), "scheduler.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.AddJob(Job{
+		Name:     "flow",
+		Action:   ActionChain,
+		Schedule: "@every 1h",
+		ChatIDs:  []int64{1},
+		Enabled:  true,
+		Steps: []JobStep{
+			{Action: ActionSendMessage, Params: map[string]string{"text": "first"}},
+			{Action: ActionSendMessage, Params: map[string]string{"text": "second"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunJobNow("flow"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("chain did not start")
+	}
+	s.Stop()
+
+	// Đã Stop: phần còn lại chỉ nằm chờ
+	time.Sleep(100 * time.Millisecond)
+	pending := s.PendingSteps()
+	if len(pending) != 1 || !pending[0].AwaitStart || pending[0].Next != 1 {
+		t.Fatalf("pending after Stop = %+v, want step 2 awaiting start", pending)
+	}
+	if count("second") != 0 {
+		t.Fatal("chain kept running after Stop")
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for count("second") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("chain did not resume after Start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := count("first"); n != 1 {
+		t.Fatalf("interrupted step sent %d times, want 1 (not replayed)", n)
+	}
+}
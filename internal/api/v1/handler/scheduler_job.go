@@ -0,0 +1,441 @@
+package v1handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dnk.com/hoc-golang/telegram"
+)
+
+// Các loại action một job có thể chạy
+const (
+	ActionSendMessage   = "send_message"
+	ActionSendPhoto     = "send_photo"
+	ActionSendDocument  = "send_document"
+	ActionSendAnimation = "send_animation"
+	ActionSendVoice     = "send_voice"
+	ActionSendVideo     = "send_video"
+	ActionGetUpdates    = "get_updates"
+
+	ActionPinMessage       = "pin_message"
+	ActionUnpinMessage     = "unpin_message"
+	ActionEditMessage      = "edit_message"
+	ActionDeleteMessage    = "delete_message"
+	ActionCreateInviteLink = "create_invite_link"
+
+	// ActionChain chạy lần lượt các Steps, bước sau dùng được message_id/invite_link của bước trước
+	ActionChain = "chain"
+)
+
+// JobRetry: số lần thử lại sau lần đầu thất bại và delay cơ sở (tăng gấp đôi mỗi lần)
+type JobRetry struct {
+	Attempts int `json:"attempts"`
+	DelaySec int `json:"delay_sec"`
+}
+
+// Job là một tác vụ định kỳ: chạy Action với Params tới từng chat trong ChatIDs theo Schedule
+type Job struct {
+	Name     string            `json:"name"`
+	Action   string            `json:"action"`
+	Schedule string            `json:"schedule"`           // cron 5/6 field, @daily, @every 5m... (xem ParseSchedule)
+	Timezone string            `json:"timezone,omitempty"` // vd Asia/Ho_Chi_Minh, rỗng = giờ máy
+	ChatIDs  []int64           `json:"chat_ids,omitempty"`
+	Params   map[string]string `json:"params,omitempty"` // send_message: text, append_time=1 để thêm giờ gửi; send_*: file_path, caption; xem jobActions
+	Steps    []JobStep         `json:"steps,omitempty"`  // chỉ dùng với action chain
+	Enabled  bool              `json:"enabled"`
+	Retry    JobRetry          `json:"retry"`
+
+	RunOnStart   bool  `json:"run_on_start,omitempty"`   // chạy ngay khi scheduler start, không chờ lịch
+	RunLimit     int   `json:"run_limit,omitempty"`      // dừng job sau số lượt này, 0 = không giới hạn
+	ReportChatID int64 `json:"report_chat_id,omitempty"` // gửi kết quả mỗi lượt vào chat này, 0 = không gửi
+
+	// CatchUp: skip, once hoặc all cho các lượt bị lỡ khi process tắt; rỗng = mặc định của Scheduler
+	CatchUp string `json:"catch_up,omitempty"`
+}
+
+// actionResult là kết quả một action: mô tả, HTTP status của Telegram và các giá trị
+// bước sau của chain tham chiếu được (message_id, message_ids, invite_link)
+type actionResult struct {
+	Text   string
+	Status int
+	Vars   map[string]string
+}
+
+// actionFunc chạy action cho một chat
+type actionFunc func(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error)
+
+type jobAction struct {
+	needsChat bool
+	required  []string // params bắt buộc
+	run       actionFunc
+}
+
+var jobActions = map[string]jobAction{
+	ActionSendMessage:   {needsChat: true, required: []string{"text"}, run: actionSendMessage},
+	ActionSendPhoto:     {needsChat: true, required: []string{"file_path"}, run: mediaAction("SendPhoto", (*telegram.TelegramClient).SendPhotoRawContext)},
+	ActionSendDocument:  {needsChat: true, required: []string{"file_path"}, run: mediaAction("SendDocument", (*telegram.TelegramClient).SendDocumentRawContext)},
+	ActionSendAnimation: {needsChat: true, required: []string{"file_path"}, run: mediaAction("SendGIF", (*telegram.TelegramClient).SendAnimationRawContext)},
+	ActionSendVoice:     {needsChat: true, required: []string{"file_path"}, run: mediaAction("SendVoice", (*telegram.TelegramClient).SendVoiceRawContext)},
+	ActionSendVideo:     {needsChat: true, required: []string{"file_path"}, run: mediaAction("SendVideo", (*telegram.TelegramClient).SendVideoRawContext)},
+	ActionGetUpdates:    {run: actionGetUpdates},
+
+	ActionPinMessage:       {needsChat: true, required: []string{"message_id"}, run: actionPinMessage},
+	ActionUnpinMessage:     {needsChat: true, run: actionUnpinMessage}, // không có message_id = bỏ ghim message mới nhất
+	ActionEditMessage:      {needsChat: true, required: []string{"message_id", "text"}, run: actionEditMessage},
+	ActionDeleteMessage:    {needsChat: true, required: []string{"message_id"}, run: actionDeleteMessage},
+	ActionCreateInviteLink: {needsChat: true, run: actionCreateInviteLink}, // name, expire_hours, member_limit tuỳ chọn
+	ActionChain:            {needsChat: true},                              // chạy bởi runChain
+}
+
+var jobNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Validate kiểm tra job và trả về lịch đã parse
+func (j *Job) Validate() (Schedule, error) {
+	if !jobNameRe.MatchString(j.Name) {
+		return nil, fmt.Errorf("invalid job name %q (a-z, 0-9, _ and -, max 64)", j.Name)
+	}
+	action, ok := jobActions[j.Action]
+	if !ok {
+		return nil, fmt.Errorf("job %s: unknown action %q", j.Name, j.Action)
+	}
+	if action.needsChat && len(j.ChatIDs) == 0 {
+		return nil, fmt.Errorf("job %s: action %s needs at least one chat_id", j.Name, j.Action)
+	}
+	if j.Action == ActionChain {
+		if err := validateSteps(j.Steps); err != nil {
+			return nil, fmt.Errorf("job %s: %v", j.Name, err)
+		}
+	} else {
+		if len(j.Steps) > 0 {
+			return nil, fmt.Errorf("job %s: steps are only allowed for action %s", j.Name, ActionChain)
+		}
+		if err := validateParams(j.Action, j.Params, 0); err != nil {
+			return nil, fmt.Errorf("job %s: %v", j.Name, err)
+		}
+	}
+	if j.Retry.Attempts < 0 || j.Retry.DelaySec < 0 {
+		return nil, fmt.Errorf("job %s: retry values must not be negative", j.Name)
+	}
+	if j.RunLimit < 0 {
+		return nil, fmt.Errorf("job %s: run_limit must not be negative", j.Name)
+	}
+	if j.CatchUp != "" {
+		if err := validateCatchUp(j.CatchUp); err != nil {
+			return nil, fmt.Errorf("job %s: %v", j.Name, err)
+		}
+	}
+	loc := time.Local
+	if j.Timezone != "" {
+		l, err := time.LoadLocation(j.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: invalid timezone %q: %v", j.Name, j.Timezone, err)
+		}
+		loc = l
+	}
+	schedule, err := ParseSchedule(j.Schedule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %v", j.Name, err)
+	}
+	return schedule, nil
+}
+
+func validateCatchUp(policy string) error {
+	switch policy {
+	case CatchUpSkip, CatchUpOnce, CatchUpAll:
+		return nil
+	}
+	return fmt.Errorf("invalid catch_up %q (skip|once|all)", policy)
+}
+
+func (j Job) clone() Job {
+	c := j
+	c.ChatIDs = append([]int64(nil), j.ChatIDs...)
+	c.Params = cloneParams(j.Params)
+	if j.Steps != nil {
+		c.Steps = make([]JobStep, len(j.Steps))
+		for i, step := range j.Steps {
+			c.Steps[i] = step
+			c.Steps[i].Params = cloneParams(step.Params)
+		}
+	}
+	return c
+}
+
+func cloneParams(params map[string]string) map[string]string {
+	if params == nil {
+		return nil
+	}
+	c := make(map[string]string, len(params))
+	for k, v := range params {
+		c[k] = v
+	}
+	return c
+}
+
+// DefaultJobs là các job mặc định như scheduler cũ: gửi text, GIF, voice, video và đọc update
+// vào cùng một chat theo cùng một lịch
+func DefaultJobs(chatID int64, schedule string, runOnStart bool, runLimit int, retry JobRetry) []Job {
+	jobs := []Job{
+		{Name: "send-message", Action: ActionSendMessage, Params: map[string]string{"text": "🚀 Scheduler: Test message", "append_time": "1"}},
+		{Name: "send-gif", Action: ActionSendAnimation, Params: map[string]string{"file_path": "./uploads/happy.gif", "caption": "🎉 Scheduler test GIF"}},
+		{Name: "send-voice", Action: ActionSendVoice, Params: map[string]string{"file_path": "./uploads/test.ogg", "caption": "🎙 Scheduler test voice"}},
+		{Name: "send-video", Action: ActionSendVideo, Params: map[string]string{"file_path": "./uploads/test_small.mp4", "caption": "🎥 Scheduler test video"}},
+		{Name: "get-updates", Action: ActionGetUpdates},
+	}
+	for i := range jobs {
+		jobs[i].Schedule = schedule
+		jobs[i].Enabled = true
+		jobs[i].Retry = retry
+		jobs[i].RunOnStart = runOnStart
+		jobs[i].RunLimit = runLimit
+		jobs[i].ReportChatID = chatID
+		if jobs[i].Action != ActionGetUpdates {
+			jobs[i].ChatIDs = []int64{chatID}
+		}
+	}
+	return jobs
+}
+
+// LoadJobsFile đọc danh sách job từ file JSON dạng [{"name": ..., "action": ..., ...}]
+func LoadJobsFile(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jobs file: %v", err)
+	}
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("decode jobs file: %v", err)
+	}
+	return jobs, nil
+}
+
+// ---------------- Actions ----------------
+
+func actionSendMessage(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error) {
+	text := params["text"]
+	if params["append_time"] == "1" {
+		text += " (" + time.Now().Format(time.RFC3339) + ")"
+	}
+	body, status, err := s.client.SendMessageRawContext(ctx, chatID, text)
+	if err != nil && body != nil {
+		// body != nil: các phần đầu đã gửi, giữ lại message_id của chúng
+		return actionResult{Status: status, Vars: sentMessageVars(body)}, fmt.Errorf("%w: %w", errPartialSend, newAPIError("SendMessage", status, err))
+	}
+	if err != nil {
+		return actionResult{Status: status}, newAPIError("SendMessage", status, err)
+	}
+	return actionResult{Text: "SendMessage ok", Status: status, Vars: sentMessageVars(body)}, nil
+}
+
+type sendFileFunc func(c *telegram.TelegramClient, ctx context.Context, chatID int64, filePath, caption string) ([]byte, int, error)
+
+// mediaAction gửi file local; file không tồn tại thì bỏ qua (không tính là lỗi) như trước
+func mediaAction(op string, send sendFileFunc) actionFunc {
+	return func(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error) {
+		filePath := params["file_path"]
+		if !checkFileExists(filePath) {
+			return actionResult{Text: fmt.Sprintf("skip %s - file not found", op)}, nil
+		}
+		body, status, err := send(s.client, ctx, chatID, filePath, params["caption"])
+		if err != nil {
+			return actionResult{Status: status}, newAPIError(op, status, err)
+		}
+		return actionResult{Text: fmt.Sprintf("%s sent: %s", op, filePath), Status: status, Vars: sentMessageVars(body)}, nil
+	}
+}
+
+// sentMessageVars lấy message_id từ response gửi message. Text dài bị chia nhiều phần thì
+// message_id là phần đầu, message_ids là mọi phần ("12,13,14") để delete_message xoá hết
+func sentMessageVars(body []byte) map[string]string {
+	// Nhiều phần thì result là mảng, nên chỉ decode result khi không có message_ids
+	var resp struct {
+		Result     json.RawMessage `json:"result"`
+		MessageIDs []int           `json:"message_ids"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	ids := resp.MessageIDs
+	if len(ids) == 0 {
+		var msg struct {
+			MessageID int `json:"message_id"`
+		}
+		if json.Unmarshal(resp.Result, &msg) != nil || msg.MessageID == 0 {
+			return nil
+		}
+		ids = []int{msg.MessageID}
+	}
+	return map[string]string{"message_id": strconv.Itoa(ids[0]), "message_ids": joinInts(ids)}
+}
+
+func joinInts(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func actionGetUpdates(ctx context.Context, s *Scheduler, _ int64, _ map[string]string) (actionResult, error) {
+	s.mu.Lock()
+	params := fmt.Sprintf("?offset=%d&limit=100&timeout=0", s.updatesOffset)
+	s.mu.Unlock()
+	body, status, err := s.client.FetchUpdatesRawContext(ctx, params)
+	if err != nil {
+		return actionResult{Status: status}, newAPIError("GetUpdates", status, err)
+	}
+
+	var result struct {
+		Ok     bool              `json:"ok"`
+		Result []telegram.Update `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return actionResult{Status: status}, newAPIError("GetUpdates", status, err)
+	}
+	if !result.Ok {
+		return actionResult{Status: status}, &APIError{Op: "GetUpdates", Status: status, Message: "telegram API returned not ok"}
+	}
+
+	count := len(result.Result)
+	if count > 0 {
+		s.mu.Lock()
+		s.updatesOffset = result.Result[count-1].UpdateID + 1
+		s.mu.Unlock()
+	}
+	return actionResult{Text: fmt.Sprintf("GetUpdates ok: %d new", count), Status: status}, nil
+}
+
+type messageFunc func(c *telegram.TelegramClient, ctx context.Context, chatID int64, messageID int) ([]byte, int, error)
+
+// messageAction chạy một method nhận chat_id + message_id (pin, delete)
+func messageAction(op string, call messageFunc) actionFunc {
+	return func(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error) {
+		messageID, err := intParam(params, "message_id")
+		if err != nil {
+			return actionResult{}, err
+		}
+		_, status, err := call(s.client, ctx, chatID, messageID)
+		if err != nil {
+			return actionResult{Status: status}, newAPIError(op, status, err)
+		}
+		return actionResult{Text: fmt.Sprintf("%s ok: %d", op, messageID), Status: status}, nil
+	}
+}
+
+var (
+	actionPinMessage    = messageAction("PinChatMessage", (*telegram.TelegramClient).PinChatMessageRawContext)
+	deleteSingleMessage = messageAction("DeleteMessage", (*telegram.TelegramClient).DeleteMessageRawContext)
+)
+
+// actionDeleteMessage xoá message_id; message_id có thể là danh sách "12,13,14"
+// (vd {{1.message_ids}} của tin dài bị chia nhiều phần), khi đó xoá bằng deleteMessages
+func actionDeleteMessage(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error) {
+	ids, err := intListParam(params, "message_id")
+	if err != nil {
+		return actionResult{}, err
+	}
+	if len(ids) == 1 {
+		return deleteSingleMessage(ctx, s, chatID, params)
+	}
+	var status int
+	for start := 0; start < len(ids); start += telegram.MaxDeleteMessages {
+		batch := ids[start:min(start+telegram.MaxDeleteMessages, len(ids))]
+		if _, status, err = s.client.DeleteMessagesRawContext(ctx, chatID, batch); err != nil {
+			return actionResult{Status: status}, newAPIError("DeleteMessages", status, err)
+		}
+	}
+	return actionResult{Text: fmt.Sprintf("DeleteMessages ok: %s", joinInts(ids)), Status: status}, nil
+}
+
+// actionUnpinMessage bỏ ghim message_id, hoặc message được ghim gần nhất nếu không có message_id
+func actionUnpinMessage(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error) {
+	var messageID int
+	if params["message_id"] != "" {
+		var err error
+		if messageID, err = intParam(params, "message_id"); err != nil {
+			return actionResult{}, err
+		}
+	}
+	_, status, err := s.client.UnpinChatMessageRawContext(ctx, chatID, messageID)
+	if err != nil {
+		return actionResult{Status: status}, newAPIError("UnpinChatMessage", status, err)
+	}
+	if messageID == 0 {
+		return actionResult{Text: "UnpinChatMessage ok: latest", Status: status}, nil
+	}
+	return actionResult{Text: fmt.Sprintf("UnpinChatMessage ok: %d", messageID), Status: status}, nil
+}
+
+func actionEditMessage(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error) {
+	messageID, err := intParam(params, "message_id")
+	if err != nil {
+		return actionResult{}, err
+	}
+	_, status, err := s.client.EditMessageTextRawContext(ctx, chatID, messageID, params["text"])
+	if err != nil {
+		return actionResult{Status: status}, newAPIError("EditMessageText", status, err)
+	}
+	return actionResult{Text: fmt.Sprintf("EditMessageText ok: %d", messageID), Status: status}, nil
+}
+
+// actionCreateInviteLink tạo link mời; expire_hours tính từ lúc chạy, member_limit 1-99999
+func actionCreateInviteLink(ctx context.Context, s *Scheduler, chatID int64, params map[string]string) (actionResult, error) {
+	var expireDate int64
+	if params["expire_hours"] != "" {
+		hours, err := intParam(params, "expire_hours")
+		if err != nil {
+			return actionResult{}, err
+		}
+		expireDate = time.Now().Add(time.Duration(hours) * time.Hour).Unix()
+	}
+	var memberLimit int
+	if params["member_limit"] != "" {
+		limit, err := intParam(params, "member_limit")
+		if err != nil {
+			return actionResult{}, err
+		}
+		memberLimit = limit
+	}
+	body, status, err := s.client.CreateChatInviteLinkRawContext(ctx, chatID, params["name"], expireDate, memberLimit)
+	if err != nil {
+		return actionResult{Status: status}, newAPIError("CreateChatInviteLink", status, err)
+	}
+	var resp struct {
+		Result telegram.ChatInviteLink `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return actionResult{Status: status}, newAPIError("CreateChatInviteLink", status, err)
+	}
+	return actionResult{
+		Text:   "CreateChatInviteLink ok: " + resp.Result.InviteLink,
+		Status: status,
+		Vars:   map[string]string{"invite_link": resp.Result.InviteLink},
+	}, nil
+}
+
+// intListParam đọc danh sách số nguyên dương cách nhau dấu phẩy
+func intListParam(params map[string]string, name string) ([]int, error) {
+	var ids []int
+	for _, v := range strings.Split(params[name], ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: %s must be positive integers separated by commas, got %q", errInvalidParam, name, params[name])
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func intParam(params map[string]string, name string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimSpace(params[name]))
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer, got %q", errInvalidParam, name, params[name])
+	}
+	return v, nil
+}
@@ -0,0 +1,174 @@
+package v1handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dnk.com/hoc-golang/telegram"
+)
+
+// newActionTestScheduler trả về scheduler gọi tới server giả, ghi lại payload của từng request.
+// respond quyết định status và body trả về; nil = luôn thành công
+func newActionTestScheduler(t *testing.T, respond func(p map[string]interface{}) (int, string)) (*Scheduler, *[]map[string]interface{}) {
+	t.Helper()
+	var payloads []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		payloads = append(payloads, p)
+		status, body := http.StatusOK, `{"ok":true,"result":{"message_id":7}}`
+		if respond != nil {
+			status, body = respond(p)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := telegram.NewTelegramClient("TOKEN", telegram.WithBaseURL(srv.URL), telegram.WithoutRateLimit())
+	s, err := NewScheduler(client, filepath.Join(t.TempDir(), "scheduler.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s, &payloads
+}
+
+func TestActionSendMessageAppendTime(t *testing.T) {
+	s, payloads := newActionTestScheduler(t, nil)
+	ctx := context.Background()
+
+	if _, err := actionSendMessage(ctx, s, 1, map[string]string{"text": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actionSendMessage(ctx, s, 1, map[string]string{"text": "hi", "append_time": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*payloads)[0]["text"]; got != "hi" {
+		t.Fatalf("text without append_time = %q, want %q", got, "hi")
+	}
+	if got, _ := (*payloads)[1]["text"].(string); !strings.HasPrefix(got, "hi (") {
+		t.Fatalf("text with append_time = %q, want timestamp suffix", got)
+	}
+}
+
+func TestActionUnpinMessageOptionalID(t *testing.T) {
+	s, payloads := newActionTestScheduler(t, nil)
+	ctx := context.Background()
+
+	if _, err := actionUnpinMessage(ctx, s, 1, map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actionUnpinMessage(ctx, s, 1, map[string]string{"message_id": "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := (*payloads)[0]["message_id"]; ok {
+		t.Fatalf("unpin without message_id sent %v", (*payloads)[0])
+	}
+	if got := (*payloads)[1]["message_id"]; got != float64(5) {
+		t.Fatalf("message_id = %v, want 5", got)
+	}
+}
+
+func TestCallWithRetryInvalidParamNotRetried(t *testing.T) {
+	s, payloads := newActionTestScheduler(t, nil)
+	job := Job{Name: "unpin", Action: ActionUnpinMessage, Retry: JobRetry{Attempts: 3, DelaySec: 1}}
+
+	_, _, err := s.callWithRetry(context.Background(), nil, job, 1, map[string]string{"message_id": "abc"}, actionUnpinMessage)
+	if !errors.Is(err, errInvalidParam) {
+		t.Fatalf("err = %v, want errInvalidParam", err)
+	}
+	if len(*payloads) != 0 {
+		t.Fatalf("sent %d requests, want 0", len(*payloads))
+	}
+}
+
+func TestCallWithRetryPartialSendNotRetried(t *testing.T) {
+	s, payloads := newActionTestScheduler(t, func(p map[string]interface{}) (int, string) {
+		if text, _ := p["text"].(string); strings.HasPrefix(text, "(2/") {
+			return http.StatusInternalServerError, `{"ok":false,"error_code":500,"description":"Internal Server Error"}`
+		}
+		return http.StatusOK, `{"ok":true,"result":{"message_id":7}}`
+	})
+	job := Job{Name: "long", Action: ActionSendMessage, Retry: JobRetry{Attempts: 2}}
+	text := strings.Repeat("a", telegram.MaxMessageLength) + "\n\n" + strings.Repeat("b", 100)
+
+	res, _, err := s.callWithRetry(context.Background(), nil, job, 1, map[string]string{"text": text}, actionSendMessage)
+	if !errors.Is(err, errPartialSend) {
+		t.Fatalf("err = %v, want errPartialSend", err)
+	}
+	if len(*payloads) != 2 {
+		t.Fatalf("sent %d requests, want 2 (no resend of delivered part)", len(*payloads))
+	}
+	if res.Vars["message_id"] != "7" {
+		t.Fatalf("vars = %v, want message_id of the delivered part", res.Vars)
+	}
+}
+
+func TestCallWithRetryMigratedChatNotCountedAsAttempt(t *testing.T) {
+	s, payloads := newActionTestScheduler(t, func(p map[string]interface{}) (int, string) {
+		if p["chat_id"] == float64(-1) {
+			return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: group chat was upgraded to a supergroup chat","parameters":{"migrate_to_chat_id":-100}}`
+		}
+		return http.StatusOK, `{"ok":true,"result":{"message_id":7}}`
+	})
+	job := Job{Name: "hello", Action: ActionSendMessage} // Retry.Attempts = 0 như job tạo qua REST
+
+	_, chatID, err := s.callWithRetry(context.Background(), nil, job, -1, map[string]string{"text": "hi"}, actionSendMessage)
+	if err != nil {
+		t.Fatalf("err = %v, want resend to migrated chat", err)
+	}
+	if chatID != -100 || len(*payloads) != 2 {
+		t.Fatalf("chat = %d after %d requests, want -100 after 2", chatID, len(*payloads))
+	}
+}
+
+func TestSentMessageVars(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string]string
+	}{
+		{name: "single", body: `{"ok":true,"result":{"message_id":12}}`, want: map[string]string{"message_id": "12", "message_ids": "12"}},
+		{name: "split", body: `{"ok":true,"message_ids":[12,13,14],"result":[{"message_id":12},{"message_id":13},{"message_id":14}]}`, want: map[string]string{"message_id": "12", "message_ids": "12,13,14"}},
+		{name: "no message", body: `{"ok":true,"result":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sentMessageVars([]byte(tt.body))
+			if len(got) != len(tt.want) || got["message_id"] != tt.want["message_id"] || got["message_ids"] != tt.want["message_ids"] {
+				t.Fatalf("vars = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionDeleteMessageList(t *testing.T) {
+	s, payloads := newActionTestScheduler(t, func(p map[string]interface{}) (int, string) {
+		return http.StatusOK, `{"ok":true,"result":true}`
+	})
+	ctx := context.Background()
+
+	if _, err := actionDeleteMessage(ctx, s, 1, map[string]string{"message_id": "12, 13,14"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actionDeleteMessage(ctx, s, 1, map[string]string{"message_id": "12"}); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := (*payloads)[0]["message_ids"].([]interface{}); len(ids) != 3 {
+		t.Fatalf("deleteMessages payload = %v, want 3 ids", (*payloads)[0])
+	}
+	if got := (*payloads)[1]["message_id"]; got != float64(12) {
+		t.Fatalf("deleteMessage payload = %v, want message_id 12", (*payloads)[1])
+	}
+	if _, err := actionDeleteMessage(ctx, s, 1, map[string]string{"message_id": "12,x"}); !errors.Is(err, errInvalidParam) {
+		t.Fatalf("err = %v, want errInvalidParam", err)
+	}
+}
@@ -0,0 +1,291 @@
+package v1handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dnk.com/hoc-golang/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// SchedulerJobHandler quản lý job của Scheduler qua REST (/api/v1/scheduler/jobs)
+type SchedulerJobHandler struct {
+	scheduler *Scheduler // nil khi scheduler chưa khởi tạo
+}
+
+type JobNameV1Param struct {
+	Name string `uri:"name" binding:"required,job_name"`
+}
+
+// JobCreateV1Param là phần riêng của body khi tạo job; các field còn lại đọc vào JobV1Param
+type JobCreateV1Param struct {
+	Name string `json:"name" binding:"required,job_name"`
+}
+
+type JobRetryV1Param struct {
+	Attempts int `json:"attempts" binding:"gte=0,lte=10"`
+	DelaySec int `json:"delay_sec" binding:"gte=0,lte=3600"`
+}
+
+// JobStepV1Param là một bước của job chain
+type JobStepV1Param struct {
+	Action string            `json:"action" binding:"required,oneof=send_message send_photo send_document send_animation send_voice send_video pin_message unpin_message edit_message delete_message create_invite_link"`
+	Params map[string]string `json:"params" binding:"omitempty"`
+	After  string            `json:"after" binding:"omitempty,max=32"`
+}
+
+// JobV1Param là body khi tạo/sửa job (không gồm name: khi tạo xem JobCreateV1Param, khi sửa lấy từ URL).
+// chat_ids, params và steps được kiểm tra theo action bằng validateJobV1Param.
+type JobV1Param struct {
+	Action       string            `json:"action" binding:"required,oneof=send_message send_photo send_document send_animation send_voice send_video get_updates pin_message unpin_message edit_message delete_message create_invite_link chain"`
+	Schedule     string            `json:"schedule" binding:"required,max=200,cron"`
+	Timezone     string            `json:"timezone" binding:"omitempty,max=64,timezone"`
+	ChatIDs      []int64           `json:"chat_ids" binding:"omitempty,max=50"`
+	Params       map[string]string `json:"params" binding:"omitempty"`
+	Steps        []JobStepV1Param  `json:"steps" binding:"omitempty,max=20,dive"`
+	Enabled      *bool             `json:"enabled" binding:"omitempty"` // mặc định true
+	Retry        JobRetryV1Param   `json:"retry"`
+	RunOnStart   bool              `json:"run_on_start"`
+	RunLimit     int               `json:"run_limit" binding:"gte=0"`
+	ReportChatID int64             `json:"report_chat_id"`
+	CatchUp      string            `json:"catch_up" binding:"omitempty,oneof=skip once all"`
+}
+
+func (p *JobV1Param) toJob(name string) Job {
+	enabled := true
+	if p.Enabled != nil {
+		enabled = *p.Enabled
+	}
+	var steps []JobStep
+	for _, step := range p.Steps {
+		steps = append(steps, JobStep{Action: step.Action, Params: step.Params, After: step.After})
+	}
+	return Job{
+		Name:         name,
+		Action:       p.Action,
+		Schedule:     p.Schedule,
+		Timezone:     p.Timezone,
+		ChatIDs:      p.ChatIDs,
+		Params:       p.Params,
+		Steps:        steps,
+		Enabled:      enabled,
+		Retry:        JobRetry{Attempts: p.Retry.Attempts, DelaySec: p.Retry.DelaySec},
+		RunOnStart:   p.RunOnStart,
+		RunLimit:     p.RunLimit,
+		ReportChatID: p.ReportChatID,
+		CatchUp:      p.CatchUp,
+	}
+}
+
+// RegisterJobValidators đăng ký tag cron, job_name (timezone có sẵn trong validator) và
+// kiểm tra JobV1Param theo action với validator của gin; gọi sau utils.RegisterValidators
+func RegisterJobValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return fmt.Errorf("failed to get validator engine")
+	}
+	if err := v.RegisterValidation("cron", func(fl validator.FieldLevel) bool {
+		_, err := ParseSchedule(fl.Field().String(), time.UTC)
+		return err == nil
+	}); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("job_name", func(fl validator.FieldLevel) bool {
+		return jobNameRe.MatchString(fl.Field().String())
+	}); err != nil {
+		return err
+	}
+	v.RegisterStructValidation(validateJobV1Param, JobV1Param{})
+	return nil
+}
+
+// validateJobV1Param kiểm tra các field phụ thuộc action như Job.Validate;
+// chi tiết lỗi nằm ở Param() để HandleValidationErrors hiển thị
+func validateJobV1Param(sl validator.StructLevel) {
+	p := sl.Current().Interface().(JobV1Param)
+	action, ok := jobActions[p.Action]
+	if !ok {
+		return // đã bị oneof báo lỗi
+	}
+	if action.needsChat && len(p.ChatIDs) == 0 {
+		sl.ReportError(p.ChatIDs, "ChatIDs", "ChatIDs", "required", "")
+	}
+	if p.Action == ActionChain {
+		if err := validateSteps(p.toJob("").Steps); err != nil {
+			sl.ReportError(p.Steps, "Steps", "Steps", "job_steps", err.Error())
+		}
+		return
+	}
+	if len(p.Steps) > 0 {
+		sl.ReportError(p.Steps, "Steps", "Steps", "job_steps", "chỉ dùng với action "+ActionChain)
+	}
+	if err := validateParams(p.Action, p.Params, 0); err != nil {
+		sl.ReportError(p.Params, "Params", "Params", "job_params", err.Error())
+	}
+}
+
+func NewSchedulerJobHandler(scheduler *Scheduler) *SchedulerJobHandler {
+	return &SchedulerJobHandler{scheduler: scheduler}
+}
+
+// ready trả lời 503 nếu scheduler chưa khởi tạo
+func (h *SchedulerJobHandler) ready(ctx *gin.Context) bool {
+	if h.scheduler == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scheduler not initialized"})
+		return false
+	}
+	return true
+}
+
+func (h *SchedulerJobHandler) ListJobs(ctx *gin.Context) {
+	if !h.ready(ctx) {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"running": h.scheduler.IsRunning(),
+		"jobs":    h.scheduler.Jobs(),
+		"pending": h.scheduler.PendingSteps(),
+	})
+}
+
+func (h *SchedulerJobHandler) GetJob(ctx *gin.Context) {
+	var uri JobNameV1Param
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	if !h.ready(ctx) {
+		return
+	}
+	job, err := h.scheduler.Job(uri.Name)
+	if err != nil {
+		jobError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, job)
+}
+
+func (h *SchedulerJobHandler) CreateJob(ctx *gin.Context) {
+	// Body được đọc hai lần: name (chỉ có khi tạo) rồi phần chung với UpdateJob
+	var create JobCreateV1Param
+	if err := ctx.ShouldBindBodyWith(&create, binding.JSON); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	var params JobV1Param
+	if err := ctx.ShouldBindBodyWith(&params, binding.JSON); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	if !h.ready(ctx) {
+		return
+	}
+	if err := h.scheduler.AddJob(params.toJob(create.Name)); err != nil {
+		jobError(ctx, err)
+		return
+	}
+	h.respondJob(ctx, http.StatusCreated, create.Name, "Job created")
+}
+
+func (h *SchedulerJobHandler) UpdateJob(ctx *gin.Context) {
+	var uri JobNameV1Param
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	var params JobV1Param
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	if !h.ready(ctx) {
+		return
+	}
+	if err := h.scheduler.UpdateJob(uri.Name, params.toJob(uri.Name)); err != nil {
+		jobError(ctx, err)
+		return
+	}
+	h.respondJob(ctx, http.StatusOK, uri.Name, "Job updated")
+}
+
+func (h *SchedulerJobHandler) DeleteJob(ctx *gin.Context) {
+	var uri JobNameV1Param
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	if !h.ready(ctx) {
+		return
+	}
+	if err := h.scheduler.RemoveJob(uri.Name); err != nil {
+		jobError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Job deleted", "name": uri.Name})
+}
+
+func (h *SchedulerJobHandler) PauseJob(ctx *gin.Context) {
+	h.setEnabled(ctx, false, "Job paused")
+}
+
+func (h *SchedulerJobHandler) ResumeJob(ctx *gin.Context) {
+	h.setEnabled(ctx, true, "Job resumed")
+}
+
+func (h *SchedulerJobHandler) setEnabled(ctx *gin.Context, enabled bool, message string) {
+	var uri JobNameV1Param
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	if !h.ready(ctx) {
+		return
+	}
+	if err := h.scheduler.SetJobEnabled(uri.Name, enabled); err != nil {
+		jobError(ctx, err)
+		return
+	}
+	h.respondJob(ctx, http.StatusOK, uri.Name, message)
+}
+
+// RunJobNow chạy một lượt ngay trong nền; kết quả xem ở last_result/last_error của GetJob
+func (h *SchedulerJobHandler) RunJobNow(ctx *gin.Context) {
+	var uri JobNameV1Param
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.HandleValidationErrors(err))
+		return
+	}
+	if !h.ready(ctx) {
+		return
+	}
+	if err := h.scheduler.RunJobNow(uri.Name); err != nil {
+		jobError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "Job run started", "name": uri.Name})
+}
+
+func (h *SchedulerJobHandler) respondJob(ctx *gin.Context, status int, name, message string) {
+	job, err := h.scheduler.Job(name)
+	if err != nil {
+		jobError(ctx, err)
+		return
+	}
+	ctx.JSON(status, gin.H{"message": message, "job": job})
+}
+
+// jobError đổi lỗi của Scheduler sang HTTP status; lỗi còn lại là job không hợp lệ
+func jobError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrJobExists), errors.Is(err, ErrJobBusy):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,54 @@
+package v1handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateJobValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := RegisterJobValidators(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		body      string
+		wantField string // field báo lỗi trong {"error": {...}}; "" = hợp lệ
+	}{
+		{name: "valid", body: `{"name":"daily","action":"send_message","schedule":"0 9 * * *","chat_ids":[1],"params":{"text":"hi"}}`},
+		{name: "missing name", body: `{"action":"send_message","schedule":"0 9 * * *","chat_ids":[1],"params":{"text":"hi"}}`, wantField: "name"},
+		{name: "bad name", body: `{"name":"Daily Job","action":"send_message","schedule":"0 9 * * *","chat_ids":[1],"params":{"text":"hi"}}`, wantField: "name"},
+		{name: "bad cron", body: `{"name":"daily","action":"send_message","schedule":"0 25 * * *","chat_ids":[1],"params":{"text":"hi"}}`, wantField: "schedule"},
+		{name: "bad timezone", body: `{"name":"daily","action":"send_message","schedule":"@daily","timezone":"Mars/Base","chat_ids":[1],"params":{"text":"hi"}}`, wantField: "timezone"},
+		{name: "missing chat", body: `{"name":"daily","action":"send_message","schedule":"@daily","params":{"text":"hi"}}`, wantField: "chat_ids"},
+		{name: "missing param", body: `{"name":"daily","action":"send_message","schedule":"@daily","chat_ids":[1]}`, wantField: "params"},
+		{name: "steps without chain", body: `{"name":"daily","action":"send_message","schedule":"@daily","chat_ids":[1],"params":{"text":"hi"},"steps":[{"action":"send_message","params":{"text":"x"}}]}`, wantField: "steps"},
+		{name: "chain bad reference", body: `{"name":"flow","action":"chain","schedule":"@daily","chat_ids":[1],"steps":[{"action":"pin_message","params":{"message_id":"{{prev.message_id}}"}}]}`, wantField: "steps"},
+		{name: "get_updates needs no chat", body: `{"name":"updates","action":"get_updates","schedule":"@every 1m"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// scheduler nil: body hợp lệ đi tới ready() và nhận 503
+			r := gin.New()
+			r.POST("/jobs", NewSchedulerJobHandler(nil).CreateJob)
+			req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if tt.wantField == "" {
+				if rec.Code != http.StatusServiceUnavailable {
+					t.Fatalf("status = %d, want 503; body %s", rec.Code, rec.Body)
+				}
+				return
+			}
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"`+tt.wantField+`":`) {
+				t.Fatalf("status = %d body %s, want 400 on %q", rec.Code, rec.Body, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package v1handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Chính sách cho các lượt bị lỡ khi process tắt hoặc scheduler bị Stop (so với due_at đã lưu)
+const (
+	CatchUpSkip = "skip" // bỏ qua, chờ lịch tiếp theo
+	CatchUpOnce = "once" // chạy bù một lượt ngay khi start
+	CatchUpAll  = "all"  // chạy bù đủ số lượt đã lỡ (tối đa maxCatchUpRuns)
+)
+
+const maxCatchUpRuns = 100
+
+// StoredJob là job kèm trạng thái chạy được lưu lại giữa các lần restart
+type StoredJob struct {
+	Job
+	Runs       int       `json:"runs"`
+	LastRunAt  time.Time `json:"last_run_at"`
+	NextRunAt  time.Time `json:"next_run_at"` // zero = job không chờ lịch lúc lưu (bị dừng/tắt)
+	DueAt      time.Time `json:"due_at"`      // lượt theo lịch chưa chạy, dùng để chạy bù
+	LastResult string    `json:"last_result,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// SchedulerState là toàn bộ dữ liệu scheduler cần lưu
+type SchedulerState struct {
+	Jobs          []StoredJob   `json:"jobs"`
+	Running       bool          `json:"running"` // đang chạy lúc lưu: restart thì Start lại
+	UpdatesOffset int           `json:"updates_offset,omitempty"`
+	Pending       []PendingStep `json:"pending,omitempty"` // bước chain đang chờ After
+}
+
+// JobStore lưu trạng thái scheduler; Load trả về nil nếu chưa có gì được lưu
+type JobStore interface {
+	Load() (*SchedulerState, error)
+	Save(state *SchedulerState) error
+}
+
+// FileJobStore lưu trạng thái vào một file JSON
+type FileJobStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileJobStore(path string) *FileJobStore {
+	return &FileJobStore{path: path}
+}
+
+func (f *FileJobStore) Load() (*SchedulerState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read scheduler store: %v", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var state SchedulerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode scheduler store: %v", err)
+	}
+	return &state, nil
+}
+
+// Save ghi ra file tạm rồi rename để không làm hỏng file nếu crash giữa chừng
+func (f *FileJobStore) Save(state *SchedulerState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// missedRuns đếm số lượt lẽ ra đã chạy từ due tới now theo policy
+func missedRuns(schedule Schedule, due, now time.Time, policy string) int {
+	if due.IsZero() || due.After(now) {
+		return 0
+	}
+	switch policy {
+	case CatchUpOnce:
+		return 1
+	case CatchUpAll:
+		n := 0
+		for t := due; !t.IsZero() && !t.After(now) && n < maxCatchUpRuns; t = schedule.Next(t) {
+			n++
+		}
+		return n
+	}
+	return 0
+}
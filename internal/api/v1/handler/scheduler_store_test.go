@@ -0,0 +1,108 @@
+package v1handler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMissedRuns(t *testing.T) {
+	every := EverySchedule{Every: time.Hour}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		due    time.Time
+		policy string
+		want   int
+	}{
+		{name: "no due", policy: CatchUpAll, want: 0},
+		{name: "due in future", due: now.Add(time.Minute), policy: CatchUpAll, want: 0},
+		{name: "skip", due: now.Add(-3 * time.Hour), policy: CatchUpSkip, want: 0},
+		{name: "once", due: now.Add(-3 * time.Hour), policy: CatchUpOnce, want: 1},
+		{name: "all", due: now.Add(-3 * time.Hour), policy: CatchUpAll, want: 4},
+		{name: "all capped", due: now.Add(-1000 * time.Hour), policy: CatchUpAll, want: maxCatchUpRuns},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missedRuns(every, tt.due, now, tt.policy); got != tt.want {
+				t.Fatalf("missedRuns = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestScheduler(t *testing.T, store JobStore) (*Scheduler, StoreInfo) {
+	t.Helper()
+	s, err := NewScheduler(nil, filepath.Join(t.TempDir(), "scheduler.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.UseStore(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, info
+}
+
+func waitForNextRun(t *testing.T, s *Scheduler, name string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, err := s.Job(name); err == nil && !job.NextRunAt.IsZero() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s never started waiting", name)
+}
+
+func TestSchedulerStorePersistsRunningAndDue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	store := NewFileJobStore(path)
+	job := Job{Name: "updates", Action: ActionGetUpdates, Schedule: "@every 1h", Enabled: true}
+
+	s, info := newTestScheduler(t, store)
+	if info.Found {
+		t.Fatalf("fresh store: info = %+v, want not found", info)
+	}
+	if err := s.AddJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitForNextRun(t, s, job.Name)
+
+	// Process bị tắt khi đang chạy: lần sau phải Start lại
+	_, info = newTestScheduler(t, store)
+	if !info.Found || info.Jobs != 1 || !info.Running {
+		t.Fatalf("after crash: info = %+v, want found, 1 job, running", info)
+	}
+
+	// Stop có chủ đích: không tự Start lại nhưng vẫn giữ lượt đang chờ để chạy bù
+	s.Stop()
+	state, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Running || state.Jobs[0].DueAt.IsZero() || !state.Jobs[0].NextRunAt.IsZero() {
+		t.Fatalf("after Stop: running=%v due=%v next=%v", state.Running, state.Jobs[0].DueAt, state.Jobs[0].NextRunAt)
+	}
+
+	// Tắt job thì bỏ lượt đang chờ
+	if err := s.SetJobEnabled(job.Name, false); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ = store.Load(); !state.Jobs[0].DueAt.IsZero() {
+		t.Fatalf("disabled job kept due_at %v", state.Jobs[0].DueAt)
+	}
+
+	// Xoá hết job: store vẫn "có", không được nạp lại job mặc định
+	if err := s.RemoveJob(job.Name); err != nil {
+		t.Fatal(err)
+	}
+	_, info = newTestScheduler(t, store)
+	if !info.Found || info.Jobs != 0 || info.Running {
+		t.Fatalf("after removing all jobs: info = %+v, want found, 0 jobs, stopped", info)
+	}
+}
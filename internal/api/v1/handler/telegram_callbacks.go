@@ -0,0 +1,83 @@
+package v1handler
+
+import (
+	"context"
+	"fmt"
+
+	"dnk.com/hoc-golang/middleware"
+	"dnk.com/hoc-golang/telegram"
+)
+
+// RegisterApprovalCallbacks đăng ký nút duyệt/từ chối với callback_data "approve:<id>" / "reject:<id>".
+// Chỉ user trong approverIDs hoặc admin của chat chứa message mới bấm được. Gửi kèm keyboard:
+//
+//	telegram.NewInlineKeyboard(telegram.NewInlineRow(
+//		telegram.InlineButtonData("✅ Duyệt", "approve:42"),
+//		telegram.InlineButtonData("❌ Từ chối", "reject:42"),
+//	))
+func (h *TelegramHandler) RegisterApprovalCallbacks(approverIDs ...int64) {
+	approvers := make(map[int64]bool, len(approverIDs))
+	for _, id := range approverIDs {
+		approvers[id] = true
+	}
+	h.Callbacks.Handle("approve:", h.approvalCallback("✅ Đã duyệt", approvers))
+	h.Callbacks.Handle("reject:", h.approvalCallback("❌ Đã từ chối", approvers))
+}
+
+// approvalCallback trả lời người bấm, gỡ keyboard để không bấm lại được và trả lời message
+// bằng kết quả; nội dung (và định dạng) của message gốc giữ nguyên
+func (h *TelegramHandler) approvalCallback(result string, approvers map[int64]bool) telegram.CallbackHandlerFunc {
+	return func(ctx context.Context, q *telegram.CallbackQuery, id string) error {
+		if !h.canApprove(ctx, q, approvers) {
+			middleware.LogTelegramInfo("🚫 Approval rejected", map[string]interface{}{"user_id": q.From.ID, "data": q.Data})
+			_, _, err := h.Client.AnswerCallbackQueryRawContext(ctx, telegram.AnswerCallbackQueryRequest{
+				CallbackQueryID: q.ID,
+				Text:            "⛔ Bạn không có quyền duyệt.",
+				ShowAlert:       true,
+			})
+			return err
+		}
+		if _, _, err := h.Client.AnswerCallbackQueryRawContext(ctx, telegram.AnswerCallbackQueryRequest{
+			CallbackQueryID: q.ID,
+			Text:            result,
+		}); err != nil {
+			return err
+		}
+		if q.Message == nil {
+			return nil
+		}
+
+		chatID, messageID := q.Message.Chat.ID, q.Message.MessageID
+		// keyboard rỗng = gỡ hết nút
+		if _, _, err := h.Client.EditMessageReplyMarkupRawContext(ctx, chatID, messageID, telegram.NewInlineKeyboard()); err != nil {
+			return err
+		}
+		who := q.From.FirstName
+		if q.From.Username != "" {
+			who = "@" + q.From.Username
+		}
+		_, _, err := h.Client.SendMessageRequestRawContext(ctx, telegram.SendMessageRequest{
+			ChatID:          chatID,
+			Text:            fmt.Sprintf("%s #%s bởi %s", result, id, who),
+			ReplyParameters: &telegram.ReplyParameters{MessageID: messageID, AllowSendingWithoutReply: true},
+		})
+		return err
+	}
+}
+
+// canApprove: user trong approvers, hoặc admin của chat chứa message; lỗi getChatMember coi như không có quyền
+func (h *TelegramHandler) canApprove(ctx context.Context, q *telegram.CallbackQuery, approvers map[int64]bool) bool {
+	userID := int64(q.From.ID)
+	if approvers[userID] {
+		return true
+	}
+	if q.Message == nil {
+		return false // inline message: không biết chat để kiểm tra admin
+	}
+	member, err := h.Client.GetChatMemberContext(ctx, q.Message.Chat.ID, userID)
+	if err != nil {
+		middleware.LogTelegramError("⚠️ getChatMember failed", err, map[string]interface{}{"chat_id": q.Message.Chat.ID, "user_id": userID})
+		return false
+	}
+	return member.IsAdmin()
+}
@@ -0,0 +1,80 @@
+package v1handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"dnk.com/hoc-golang/telegram"
+)
+
+func TestApprovalCallbackRights(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    int
+		status    string // status của người bấm trong chat (getChatMember)
+		wantCalls []string
+	}{
+		{name: "member rejected", userID: 5, status: "member", wantCalls: []string{"getChatMember", "answerCallbackQuery"}},
+		{name: "chat admin", userID: 5, status: "administrator", wantCalls: []string{"getChatMember", "answerCallbackQuery", "editMessageReplyMarkup", "sendMessage"}},
+		{name: "approver", userID: 7, wantCalls: []string{"answerCallbackQuery", "editMessageReplyMarkup", "sendMessage"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var calls []string
+			var answer map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var p map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&p)
+				method := path.Base(r.URL.Path)
+				mu.Lock()
+				calls = append(calls, method)
+				if method == "answerCallbackQuery" {
+					answer = p
+				}
+				mu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				switch method {
+				case "getChatMember":
+					_, _ = w.Write([]byte(`{"ok":true,"result":{"status":"` + tt.status + `","user":{"id":5}}}`))
+				case "sendMessage":
+					_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":11}}`))
+				default:
+					_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+				}
+			}))
+			defer srv.Close()
+
+			h := NewTelegramHandler(telegram.NewTelegramClient("TOKEN", telegram.WithBaseURL(srv.URL), telegram.WithoutRateLimit()))
+			h.RegisterApprovalCallbacks(7)
+			update := &telegram.Update{UpdateID: 1, CallbackQuery: &telegram.CallbackQuery{
+				ID:      "q1",
+				From:    telegram.User{ID: tt.userID, FirstName: "An"},
+				Message: &telegram.Message{MessageID: 10, Chat: telegram.Chat{ID: -100}, Text: "Yêu cầu #42"},
+				Data:    "approve:42",
+			}}
+			if err := h.Dispatcher.Handle(context.Background(), update); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Fatalf("calls = %v, want %v", calls, tt.wantCalls)
+				}
+			}
+			if tt.status == "member" && answer["show_alert"] != true {
+				t.Fatalf("answer = %v, want alert", answer)
+			}
+		})
+	}
+}
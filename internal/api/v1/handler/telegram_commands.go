@@ -0,0 +1,50 @@
+package v1handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dnk.com/hoc-golang/telegram"
+)
+
+// RegisterDefaultCommands đăng ký /start, /help và câu trả lời cho tin nhắn không phải lệnh
+func (h *TelegramHandler) RegisterDefaultCommands() {
+	h.Dispatcher.OnCommand("start", "Bắt đầu sử dụng bot", h.startCommand)
+	h.Dispatcher.OnCommand("help", "Danh sách lệnh", h.helpCommand)
+	h.Dispatcher.On(telegram.UpdateMessage, h.unknownMessage)
+}
+
+func (h *TelegramHandler) startCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	name := "bạn"
+	if msg.From != nil && msg.From.FirstName != "" {
+		name = msg.From.FirstName
+	}
+	text := fmt.Sprintf("👋 Xin chào %s! Gõ /help để xem các lệnh.", name)
+	_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, text)
+	return err
+}
+
+func (h *TelegramHandler) helpCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	var b strings.Builder
+	b.WriteString("📖 Các lệnh:\n")
+	for _, c := range h.Dispatcher.Commands() {
+		fmt.Fprintf(&b, "/%s — %s\n", c.Command, c.Description)
+	}
+	_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, b.String())
+	return err
+}
+
+// unknownMessage chỉ trả lời trong chat riêng; trong group bot im lặng để không làm phiền
+func (h *TelegramHandler) unknownMessage(ctx context.Context, u *telegram.Update) error {
+	msg := u.Message
+	if msg.Chat.Type != "private" {
+		return nil
+	}
+	text := "🤔 Mình chưa hiểu tin nhắn này. Gõ /help để xem các lệnh."
+	if cmd, ok := telegram.ParseCommand(msg.Text); ok {
+		text = fmt.Sprintf("🤔 Không có lệnh /%s. Gõ /help để xem các lệnh.", cmd.Name)
+	}
+	_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, text)
+	return err
+}
@@ -0,0 +1,239 @@
+package v1handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dnk.com/hoc-golang/middleware"
+	"dnk.com/hoc-golang/telegram"
+)
+
+// Các lựa chọn thời gian ban; "0" = vĩnh viễn
+var banDurations = []struct {
+	label   string
+	seconds int64
+}{
+	{"1 giờ", 3600},
+	{"1 ngày", 86400},
+	{"7 ngày", 7 * 86400},
+	{"Vĩnh viễn", 0},
+}
+
+// RegisterConversations bật hội thoại nhiều bước (cần h.Conversations khác nil):
+// /ban → chọn người → chọn thời gian → xác nhận, /cancel để huỷ giữa chừng
+func (h *TelegramHandler) RegisterConversations() {
+	if h.Conversations == nil {
+		return
+	}
+	h.Conversations.Register(&telegram.Flow{
+		Name:  "ban",
+		Start: "user",
+		Steps: map[string]telegram.StepHandlerFunc{
+			"user":     h.banPickUser,
+			"duration": h.banPickDuration,
+			"confirm":  h.banConfirm,
+		},
+	})
+	h.Conversations.OnAbort = h.conversationAborted
+	h.Dispatcher.OnCommand("ban", "Ban thành viên (từng bước)", h.banCommand)
+	// Chỉ tới đây khi không có hội thoại nào, vì hội thoại đang dở đã bị middleware bắt trước
+	h.Dispatcher.OnCommand("cancel", "Huỷ thao tác đang làm", h.cancelCommand)
+}
+
+func (h *TelegramHandler) banCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	if msg.Chat.Type == "private" {
+		_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, "⚠️ /ban chỉ dùng trong group.")
+		return err
+	}
+	key, ok := telegram.ConversationKeyFor(&telegram.Update{Message: msg})
+	if !ok {
+		return nil
+	}
+	if !h.canBan(ctx, msg.Chat.ID, key.UserID) {
+		_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, "⛔ Chỉ admin có quyền ban thành viên mới dùng được /ban.")
+		return err
+	}
+	if err := h.Conversations.Begin(key, "ban", nil); err != nil {
+		return err
+	}
+	text := "👤 Reply một tin nhắn của người cần ban hoặc gửi user ID của họ. Gõ /cancel để huỷ."
+	_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, text)
+	return err
+}
+
+// canBan: userID là creator hoặc admin có quyền ban (can_restrict_members) trong chat.
+// Lỗi khi hỏi Telegram thì coi như không có quyền.
+func (h *TelegramHandler) canBan(ctx context.Context, chatID, userID int64) bool {
+	member, err := h.Client.GetChatMemberContext(ctx, chatID, userID)
+	if err != nil {
+		middleware.LogTelegramError("⚠️ getChatMember failed", err, map[string]interface{}{"chat_id": chatID, "user_id": userID})
+		return false
+	}
+	return member.Status == "creator" || (member.IsAdmin() && member.CanRestrictMembers)
+}
+
+func (h *TelegramHandler) cancelCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, "ℹ️ Không có thao tác nào đang làm.")
+	return err
+}
+
+// banPickUser nhận người cần ban từ tin nhắn được reply hoặc từ user ID gõ vào
+func (h *TelegramHandler) banPickUser(ctx context.Context, conv *telegram.Conversation, u *telegram.Update) error {
+	msg := u.Message
+	if msg == nil {
+		return nil
+	}
+	var userID int64
+	name := ""
+	if reply := msg.ReplyToMessage; reply != nil && reply.From != nil {
+		userID, name = int64(reply.From.ID), reply.From.FirstName
+	} else if id, err := strconv.ParseInt(strings.TrimSpace(msg.Text), 10, 64); err == nil && id > 0 {
+		userID, name = id, strconv.FormatInt(id, 10)
+	} else {
+		_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, "⚠️ Hãy reply tin nhắn của người đó hoặc gửi user ID dạng số.")
+		return err
+	}
+
+	conv.Set("user_id", strconv.FormatInt(userID, 10))
+	conv.Set("name", name)
+	var buttons []telegram.InlineKeyboardButton
+	for _, d := range banDurations {
+		buttons = append(buttons, telegram.InlineButtonData(d.label, "ban_duration:"+strconv.FormatInt(d.seconds, 10)))
+	}
+	_, _, err := h.Client.SendMessageRequestRawContext(ctx, telegram.SendMessageRequest{
+		ChatID:      msg.Chat.ID,
+		Text:        fmt.Sprintf("⏱ Ban %s trong bao lâu?", name),
+		ReplyMarkup: telegram.NewInlineKeyboard(telegram.NewInlineRow(buttons...)),
+	})
+	if err != nil {
+		return err
+	}
+	conv.Next("duration")
+	return nil
+}
+
+func (h *TelegramHandler) banPickDuration(ctx context.Context, conv *telegram.Conversation, u *telegram.Update) error {
+	value, ok := conversationCallback(u, "ban_duration:")
+	if !ok {
+		return h.remindButtons(ctx, u)
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+		return fmt.Errorf("invalid ban duration %q", value)
+	}
+	if err := h.answerConversationCallback(ctx, u.CallbackQuery, ""); err != nil {
+		return err
+	}
+	conv.Set("duration", value)
+
+	q := u.CallbackQuery
+	text := fmt.Sprintf("❓ Ban %s (%s)?", conv.Get("name"), banDurationLabel(value))
+	keyboard := telegram.NewInlineKeyboard(telegram.NewInlineRow(
+		telegram.InlineButtonData("✅ Xác nhận", "ban_confirm:yes"),
+		telegram.InlineButtonData("❌ Huỷ", "ban_confirm:no"),
+	))
+	if _, _, err := h.Client.EditMessageTextMarkupRawContext(ctx, q.Message.Chat.ID, q.Message.MessageID, text, keyboard); err != nil {
+		return err
+	}
+	conv.Next("confirm")
+	return nil
+}
+
+func (h *TelegramHandler) banConfirm(ctx context.Context, conv *telegram.Conversation, u *telegram.Update) error {
+	value, ok := conversationCallback(u, "ban_confirm:")
+	if !ok {
+		return h.remindButtons(ctx, u)
+	}
+	q := u.CallbackQuery
+	noButtons := telegram.NewInlineKeyboard()
+	if value != "yes" {
+		conv.End()
+		if err := h.answerConversationCallback(ctx, q, "Đã huỷ"); err != nil {
+			return err
+		}
+		_, _, err := h.Client.EditMessageTextMarkupRawContext(ctx, q.Message.Chat.ID, q.Message.MessageID, "🚫 Đã huỷ ban.", noButtons)
+		return err
+	}
+
+	// Kiểm tra lại: quyền có thể bị gỡ trong lúc đang chọn
+	if !h.canBan(ctx, q.Message.Chat.ID, int64(q.From.ID)) {
+		conv.End()
+		_ = h.answerConversationCallback(ctx, q, "⛔ Bạn không còn quyền ban")
+		_, _, err := h.Client.EditMessageTextMarkupRawContext(ctx, q.Message.Chat.ID, q.Message.MessageID, "⛔ Đã huỷ ban: không có quyền.", noButtons)
+		return err
+	}
+
+	userID, err := strconv.ParseInt(conv.Get("user_id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user_id in conversation: %v", err)
+	}
+	seconds, _ := strconv.ParseInt(conv.Get("duration"), 10, 64)
+	var untilDate int64
+	if seconds > 0 {
+		untilDate = time.Now().Add(time.Duration(seconds) * time.Second).Unix()
+	}
+	if _, _, err := h.Client.BanChatMemberRawContext(ctx, q.Message.Chat.ID, userID, untilDate); err != nil {
+		// Báo lỗi và kết thúc để user không bị kẹt ở bước xác nhận; chi tiết chỉ ghi log, không gửi vào group
+		conv.End()
+		middleware.LogTelegramError("❌ banChatMember failed", err, map[string]interface{}{"chat_id": q.Message.Chat.ID, "user_id": userID})
+		_ = h.answerConversationCallback(ctx, q, "❌ Không ban được")
+		_, _, editErr := h.Client.EditMessageTextMarkupRawContext(ctx, q.Message.Chat.ID, q.Message.MessageID, "❌ Ban thất bại, vui lòng thử lại sau.", noButtons)
+		return editErr
+	}
+	conv.End()
+	if err := h.answerConversationCallback(ctx, q, "✅ Đã ban"); err != nil {
+		return err
+	}
+	text := fmt.Sprintf("✅ Đã ban %s (%s).", conv.Get("name"), banDurationLabel(conv.Get("duration")))
+	_, _, err = h.Client.EditMessageTextMarkupRawContext(ctx, q.Message.Chat.ID, q.Message.MessageID, text, noButtons)
+	return err
+}
+
+// conversationAborted báo cho user khi hội thoại bị huỷ hoặc hết hạn
+func (h *TelegramHandler) conversationAborted(ctx context.Context, key telegram.ConversationKey, state *telegram.ConversationState, reason string) {
+	text := "🚫 Đã huỷ."
+	if reason == telegram.ConversationTimedOut {
+		text = "⌛ Hết thời gian chờ, thao tác đã bị huỷ."
+	}
+	_, _, _ = h.Client.SendMessageRawContext(ctx, key.ChatID, text)
+}
+
+// remindButtons nhắc user bấm nút thay vì gõ chữ; callback lạ thì chỉ tắt loading trên nút
+func (h *TelegramHandler) remindButtons(ctx context.Context, u *telegram.Update) error {
+	if u.CallbackQuery != nil {
+		return h.answerConversationCallback(ctx, u.CallbackQuery, "")
+	}
+	if u.Message == nil {
+		return nil
+	}
+	_, _, err := h.Client.SendMessageRawContext(ctx, u.Message.Chat.ID, "👆 Hãy chọn bằng các nút ở trên, hoặc /cancel để huỷ.")
+	return err
+}
+
+func (h *TelegramHandler) answerConversationCallback(ctx context.Context, q *telegram.CallbackQuery, text string) error {
+	_, _, err := h.Client.AnswerCallbackQueryRawContext(ctx, telegram.AnswerCallbackQueryRequest{
+		CallbackQueryID: q.ID,
+		Text:            text,
+	})
+	return err
+}
+
+// conversationCallback trả về phần sau prefix nếu update là callback query có data bắt đầu bằng prefix
+func conversationCallback(u *telegram.Update, prefix string) (string, bool) {
+	q := u.CallbackQuery
+	if q == nil || q.Message == nil || !strings.HasPrefix(q.Data, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(q.Data, prefix), true
+}
+
+func banDurationLabel(seconds string) string {
+	for _, d := range banDurations {
+		if strconv.FormatInt(d.seconds, 10) == seconds {
+			return strings.ToLower(d.label)
+		}
+	}
+	return seconds + "s"
+}
@@ -0,0 +1,130 @@
+package v1handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"dnk.com/hoc-golang/telegram"
+	"github.com/gin-gonic/gin"
+)
+
+// telegramFilesDir nằm trong ./uploads nên file đã cache được phục vụ qua route tĩnh /images
+const (
+	telegramFilesDir = "uploads/telegram"
+	telegramFilesURL = "/images/telegram"
+)
+
+// GetFile tải file user gửi cho bot theo file_id:
+//   - mặc định: lưu vào ./uploads/telegram/<file_unique_id><ext> (bỏ qua nếu đã có) và trả về URL /images/...
+//   - ?mode=proxy: stream thẳng nội dung về client, không lưu
+//   - ?mode=download: như mặc định nhưng trả về nội dung file thay vì JSON
+func (h *TelegramHandler) GetFile(c *gin.Context) {
+	fileID := c.Param("file_id")
+	mode := c.DefaultQuery("mode", "cache")
+	if mode != "cache" && mode != "proxy" && mode != "download" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be cache, proxy or download"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	file, err := h.Client.GetFileContext(ctx, fileID)
+	if err != nil {
+		respondFileError(c, err)
+		return
+	}
+
+	if mode == "proxy" {
+		if ct := mime.TypeByExtension(path.Ext(file.FilePath)); ct != "" {
+			c.Header("Content-Type", ct)
+		} else {
+			c.Header("Content-Type", "application/octet-stream")
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": path.Base(file.FilePath)}))
+		c.Status(http.StatusOK)
+		if _, err := h.Client.DownloadFilePathContext(ctx, file.FilePath, c.Writer); err != nil {
+			// Header đã gửi đi, chỉ còn cách huỷ response
+			c.Error(err)
+			c.Abort()
+		}
+		return
+	}
+
+	name := cachedFileName(file)
+	localPath := filepath.Join(telegramFilesDir, name)
+	cached := true
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		cached = false
+		if err := h.downloadToFile(c, file, localPath); err != nil {
+			respondFileError(c, err)
+			return
+		}
+	}
+
+	if mode == "download" {
+		c.FileAttachment(localPath, path.Base(file.FilePath))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"file_id":        file.FileID,
+		"file_unique_id": file.FileUniqueID,
+		"file_size":      file.FileSize,
+		"local_path":     localPath,
+		"url":            telegramFilesURL + "/" + name,
+		"cached":         cached,
+	})
+}
+
+// downloadToFile tải vào file tạm cùng thư mục rồi rename, tránh để lại file dở dang khi lỗi
+func (h *TelegramHandler) downloadToFile(c *gin.Context, file *telegram.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := h.Client.DownloadFilePathContext(c.Request.Context(), file.FilePath, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp tạo file 0600, cho phép đọc như các file khác trong ./uploads
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// cachedFileName đặt tên theo file_unique_id (cố định cho cùng một file, khác bot vẫn trùng)
+// và giữ đuôi file từ file_path của Telegram
+func cachedFileName(file *telegram.File) string {
+	id := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return -1
+	}, file.FileUniqueID)
+	if id == "" {
+		id = "file"
+	}
+	return id + strings.ToLower(path.Ext(file.FilePath))
+}
+
+func respondFileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, telegram.ErrBadRequest):
+		// file_id sai hoặc file lớn hơn 20 MB (giới hạn getFile của Bot API)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot get file", "detail": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "telegram error", "detail": err.Error()})
+	}
+}
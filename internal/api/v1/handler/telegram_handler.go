@@ -1,31 +1,54 @@
 package v1handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"dnk.com/hoc-golang/middleware"
 	"dnk.com/hoc-golang/telegram"
 	"github.com/gin-gonic/gin"
 )
 
 type TelegramHandler struct {
-	Client *telegram.TelegramClient
+	Client     *telegram.TelegramClient
+	Dispatcher *telegram.Dispatcher
+	Callbacks  *telegram.CallbackRouter // = Dispatcher.Callbacks
+
+	// Seen chống xử lý trùng khi Telegram gửi lại update; nil = không kiểm tra
+	Seen *telegram.SeenUpdates
+	// Updates xử lý webhook bất đồng bộ; nil = xử lý ngay trong request
+	Updates *telegram.UpdateWorkerPool
+	// Conversations giữ hội thoại nhiều bước (/ban...); nil = tắt
+	Conversations *telegram.ConversationManager
+	// Scheduler điều khiển qua lệnh /scheduler_*; nil = chưa khởi tạo
+	Scheduler *Scheduler
+	// WebhookSecret là secret route webhook kiểm tra (TELEGRAM_WEBHOOK_SECRET), SetWebhook chỉ đăng ký secret này
+	WebhookSecret string
+
+	schedulerAccess SchedulerAccess
 }
 
-func NewTelegramHandler(token string) *TelegramHandler {
+func NewTelegramHandler(client *telegram.TelegramClient) *TelegramHandler {
+	dispatcher := telegram.NewDispatcher(client)
 	return &TelegramHandler{
-		Client: telegram.NewTelegramClient(token),
+		Client:     client,
+		Dispatcher: dispatcher,
+		Callbacks:  dispatcher.Callbacks,
 	}
 }
 
-// We'll simply log and (optionally) send a text reply if message present.
+// HandleUpdate nhận update từ webhook. update_id đã nhận rồi thì bỏ qua (Telegram gửi lại khi
+// phản hồi chậm/lỗi); có worker pool thì trả 200 ngay và xử lý nền, không thì xử lý như long polling.
 func (h *TelegramHandler) HandleUpdate(c *gin.Context) {
 	var update telegram.Update
 	if err := c.ShouldBindJSON(&update); err != nil {
@@ -33,31 +56,48 @@ func (h *TelegramHandler) HandleUpdate(c *gin.Context) {
 		return
 	}
 
-	if update.Message != nil {
-		chatID := update.Message.Chat.ID
-		text := update.Message.Text
-		reply := fmt.Sprintf("Bạn gửi: %s", text)
-		data, status, err := h.Client.SendMessageRaw(chatID, reply)
+	if h.Seen != nil {
+		fresh, err := h.Seen.TryMark(update.UpdateID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":           "failed to send reply",
-				"detail":          err.Error(),
-				"telegram_status": status,
-				"telegram_body":   string(data)})
+			middleware.LogTelegramError("⚠️ Cannot persist seen update_id", err, map[string]interface{}{"update_id": update.UpdateID})
+		}
+		if !fresh {
+			c.JSON(http.StatusOK, gin.H{"message": "duplicate update ignored", "update_id": update.UpdateID})
 			return
 		}
-		c.Data(http.StatusOK, "application/json", data)
+	}
+
+	if h.Updates != nil {
+		if err := h.Updates.Submit(&update); err != nil {
+			// Chưa xử lý: bỏ đánh dấu để lần Telegram gửi lại được nhận
+			if h.Seen != nil {
+				_ = h.Seen.Forget(update.UpdateID)
+			}
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "update_id": update.UpdateID})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "update queued", "update_id": update.UpdateID})
+		return
+	}
+
+	if err := h.ProcessUpdate(c.Request.Context(), &update); err != nil {
+		// Handler có thể đã trả lời một phần (hoặc lỗi vĩnh viễn như bot bị chặn): vẫn trả 200 và
+		// giữ đánh dấu để Telegram không gửi lại, giống lỗi trong worker pool
+		middleware.LogTelegramError("❌ Update handler failed", err, map[string]interface{}{"update_id": update.UpdateID, "kind": update.Kind()})
+		c.JSON(http.StatusOK, gin.H{"message": "update failed", "update_id": update.UpdateID})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "update processed", "update_id": update.UpdateID})
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "no action taken"})
+// ProcessUpdate chuyển update cho Dispatcher; dùng chung cho webhook và telegram.Poller
+func (h *TelegramHandler) ProcessUpdate(ctx context.Context, update *telegram.Update) error {
+	return h.Dispatcher.Handle(ctx, update)
 }
 
 // ---- Request structs ----
-type SendMessageRequest struct {
-	ChatID interface{} `json:"chat_id" form:"chat_id"`
-	Text   string      `json:"text" form:"text" binding:"required"`
-}
+// SendMessageRequest dùng chung struct với telegram để nhận đủ parse_mode, entities, reply...
+type SendMessageRequest = telegram.SendMessageRequest
 
 type SendMediaRequest struct {
 	ChatID   interface{} `json:"chat_id" form:"chat_id"`
@@ -69,6 +109,9 @@ type SendMediaRequest struct {
 func normalizeChatID(chatID interface{}) (int64, error) {
 	switch v := chatID.(type) {
 	case string:
+		if v == "" {
+			return normalizeChatID(nil)
+		}
 		return strconv.ParseInt(v, 10, 64)
 	case float64:
 		return int64(v), nil
@@ -87,7 +130,7 @@ func normalizeChatID(chatID interface{}) (int64, error) {
 	}
 }
 
-// parseMediaRequest supports application/json, x-www-form-urlencoded, multipart/form-data
+// parseMediaRequest supports application/json, x-www-form-urlencoded (multipart: xem sendMultipartMedia)
 func parseMediaRequest(c *gin.Context) (int64, string, string, error) {
 	ct := c.ContentType()
 
@@ -112,40 +155,6 @@ func parseMediaRequest(c *gin.Context) (int64, string, string, error) {
 		return chatID, req.FilePath, req.Caption, nil
 	}
 
-	// multipart/form-data: handle file upload
-	if strings.HasPrefix(ct, "multipart/form-data") {
-		chatIDStr := c.PostForm("chat_id")
-		if chatIDStr == "" {
-			env := os.Getenv("TELEGRAM_CHAT_ID")
-			if env == "" {
-				return 0, "", "", fmt.Errorf("missing chat_id in form and TELEGRAM_CHAT_ID not set")
-			}
-			chatIDStr = env
-		}
-		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
-		if err != nil {
-			return 0, "", "", err
-		}
-		caption := c.PostForm("caption")
-
-		// file can be provided as field "file" — save to temp and return path
-		file, errFile := c.FormFile("file")
-		if errFile == nil && file != nil {
-			dst := filepath.Join(os.TempDir(), file.Filename)
-			if saveErr := c.SaveUploadedFile(file, dst); saveErr != nil {
-				return 0, "", "", saveErr
-			}
-			return chatID, dst, caption, nil
-		}
-
-		// fallback: client may pass file_path form field (URL or server path)
-		fp := c.PostForm("file_path")
-		if fp == "" {
-			return 0, "", "", fmt.Errorf("no file uploaded and file_path not provided")
-		}
-		return chatID, fp, caption, nil
-	}
-
 	return 0, "", "", fmt.Errorf("unsupported Content-Type: %s", ct)
 }
 
@@ -160,40 +169,11 @@ func (h *TelegramHandler) SendMessage(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-	case strings.HasPrefix(ct, "application/x-www-form-urlencoded"):
+	case strings.HasPrefix(ct, "application/x-www-form-urlencoded"), strings.HasPrefix(ct, "multipart/form-data"):
 		if err := c.ShouldBind(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-	case strings.HasPrefix(ct, "multipart/form-data"):
-		req.ChatID = c.PostForm("chat_id")
-		req.Text = c.PostForm("text")
-		if req.Text == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "text required"})
-			return
-		}
-		// ---- Bổ sung dùng postFormURLEncoded ----
-		chatID, err := normalizeChatID(req.ChatID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		form := map[string]string{
-			"chat_id": strconv.FormatInt(chatID, 10),
-			"text":    req.Text,
-		}
-		data, status, err := h.Client.PostFormURLEncoded("sendMessage", form)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":  err.Error(),
-				"status": status,
-				"body":   string(data),
-			})
-			return
-		}
-		c.Data(http.StatusOK, "application/json", data)
-
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported content-type", "type": ct})
 		return
@@ -204,9 +184,14 @@ func (h *TelegramHandler) SendMessage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.ChatID = chatID
+	if err := telegram.ValidateReplyMarkup(req.ReplyMarkup); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Gọi Telegram API và nhận raw data
-	data, status, err := h.Client.SendMessageRaw(chatID, req.Text)
+	data, status, err := h.Client.SendMessageRequestRawContext(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  "telegram error",
@@ -231,7 +216,11 @@ func (h *TelegramHandler) SendMessage(c *gin.Context) {
 }
 
 // ---- Generic media handler factory ----
-func (h *TelegramHandler) sendMediaWrapper(c *gin.Context, sendFunc func(int64, string, string) ([]byte, int, error)) {
+func (h *TelegramHandler) sendMediaWrapper(c *gin.Context, method, field string, sendFunc func(context.Context, int64, string, string) ([]byte, int, error)) {
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		h.sendMultipartMedia(c, method, field, sendFunc)
+		return
+	}
 	chatID, filePath, caption, err := parseMediaRequest(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -244,31 +233,190 @@ func (h *TelegramHandler) sendMediaWrapper(c *gin.Context, sendFunc func(int64,
 			return
 		}
 	}
-	data, status, err := sendFunc(chatID, filePath, caption)
+	data, status, err := sendFunc(c.Request.Context(), chatID, filePath, caption)
+	respondMediaResult(c, data, status, err)
+}
+
+// sendMultipartMedia đọc multipart theo từng part thay vì parse cả form vào RAM/disk.
+// Nếu chat_id đứng trước part "file" thì file được chuyển thẳng sang Telegram (không retry);
+// các field sau part "file" khi đó bị bỏ qua, nên client nên gửi chat_id, caption trước file.
+// Nếu part "file" tới trước chat_id thì spool ra thư mục tạm, gửi xong sẽ xoá.
+func (h *TelegramHandler) sendMultipartMedia(c *gin.Context, method, field string, sendFunc func(context.Context, int64, string, string) ([]byte, int, error)) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Client.MaxUploadSize(field)+multipartOverhead)
+	mr, err := c.Request.MultipartReader()
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form", "detail": err.Error()})
+		return
+	}
+
+	fields := map[string]string{}
+	var spoolPath string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form", "detail": err.Error()})
+			return
+		}
+
+		if part.FormName() != "file" || part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form", "detail": err.Error()})
+				return
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+
+		filename := filepath.Base(part.FileName())
+		counter := &countingReader{r: part}
+		c.Set(middleware.StreamedFilesKey, []map[string]any{{
+			"field":        part.FormName(),
+			"filename":     filename,
+			"content_type": part.Header.Get("Content-Type"),
+			"size":         counter,
+		}})
+
+		if _, ok := fields["chat_id"]; ok {
+			chatID, err := normalizeChatID(fields["chat_id"])
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx := telegram.ContextWithProgress(c.Request.Context(), logUploadProgress(method, filename))
+			data, status, err := h.Client.SendMediaReaderRawContext(ctx, method, chatID, field, filename, counter, -1,
+				map[string]string{"caption": fields["caption"]})
+			respondMediaResult(c, data, status, err)
+			return
+		}
+
+		dir, err := os.MkdirTemp("", "tg-upload-*")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save upload", "detail": err.Error()})
+			return
+		}
+		defer os.RemoveAll(dir)
+		spoolPath = filepath.Join(dir, filename)
+		if err := spoolToFile(spoolPath, counter); err != nil {
+			status := http.StatusInternalServerError
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.JSON(status, gin.H{"error": "cannot save upload", "file": filename, "detail": err.Error()})
+			return
+		}
+	}
+
+	chatID, err := normalizeChatID(fields["chat_id"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filePath := spoolPath
+	if filePath == "" {
+		// fallback: client may pass file_path form field (URL or server path)
+		filePath = fields["file_path"]
+		if filePath == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no file uploaded and file_path not provided"})
+			return
+		}
+	}
+	ctx := telegram.ContextWithProgress(c.Request.Context(), logUploadProgress(method, filepath.Base(filePath)))
+	data, status, err := sendFunc(ctx, chatID, filePath, fields["caption"])
+	respondMediaResult(c, data, status, err)
+}
+
+// uploadProgressStep: cứ gửi thêm chừng này byte thì log tiến độ một lần
+const uploadProgressStep = 10 << 20
+
+// logUploadProgress trả về ProgressFunc ghi tiến độ upload lên Telegram vào telegram log
+func logUploadProgress(method, filename string) telegram.ProgressFunc {
+	var last, next int64
+	return func(sent, total int64) {
+		if sent < last {
+			next = 0 // lần retry mới, đếm lại từ đầu
+		}
+		last = sent
+		if sent < next && sent != total {
+			return
+		}
+		next = (sent/uploadProgressStep + 1) * uploadProgressStep
+		fields := map[string]interface{}{"method": method, "file": filename, "sent": middleware.FormatFileSize(sent)}
+		if total > 0 {
+			fields["total"] = middleware.FormatFileSize(total)
+		}
+		middleware.LogTelegramInfo("upload progress", fields)
+	}
+}
+
+// multipartOverhead là phần body cho phép thêm ngoài file (boundary, chat_id, caption...)
+const multipartOverhead = 1 << 20
+
+// maxFormValueSize giới hạn mỗi field text trong multipart
+const maxFormValueSize = 64 << 10
+
+func respondMediaResult(c *gin.Context, data []byte, status int, err error) {
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.Is(err, telegram.ErrFileTooLarge) || errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large", "detail": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "telegram error", "detail": err.Error(), "status": status, "body": string(data)})
 		return
 	}
 	c.Data(http.StatusOK, "application/json", data)
 }
 
+// countingReader đếm số byte đã đọc; log ra dạng "1.23 MB" qua MarshalJSON
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) MarshalJSON() ([]byte, error) {
+	return json.Marshal(middleware.FormatFileSize(r.n))
+}
+
+func spoolToFile(path string, r io.Reader) error {
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
 func (h *TelegramHandler) SendPhoto(c *gin.Context) {
-	h.sendMediaWrapper(c, h.Client.SendPhotoRaw)
+	h.sendMediaWrapper(c, "sendPhoto", "photo", h.Client.SendPhotoRawContext)
 }
 func (h *TelegramHandler) SendAudio(c *gin.Context) {
-	h.sendMediaWrapper(c, h.Client.SendAudioRaw)
+	h.sendMediaWrapper(c, "sendAudio", "audio", h.Client.SendAudioRawContext)
 }
 func (h *TelegramHandler) SendDocument(c *gin.Context) {
-	h.sendMediaWrapper(c, h.Client.SendDocumentRaw)
+	h.sendMediaWrapper(c, "sendDocument", "document", h.Client.SendDocumentRawContext)
 }
 func (h *TelegramHandler) SendVideo(c *gin.Context) {
-	h.sendMediaWrapper(c, h.Client.SendVideoRaw)
+	h.sendMediaWrapper(c, "sendVideo", "video", h.Client.SendVideoRawContext)
 }
 func (h *TelegramHandler) SendAnimation(c *gin.Context) {
-	h.sendMediaWrapper(c, h.Client.SendAnimationRaw)
+	h.sendMediaWrapper(c, "sendAnimation", "animation", h.Client.SendAnimationRawContext)
 }
 func (h *TelegramHandler) SendVoice(c *gin.Context) {
-	h.sendMediaWrapper(c, h.Client.SendVoiceRaw)
+	h.sendMediaWrapper(c, "sendVoice", "voice", h.Client.SendVoiceRawContext)
 }
 
 // ---- GetUpdates (support offset & reset) ----
@@ -291,7 +439,7 @@ func (h *TelegramHandler) GetUpdates(c *gin.Context) {
 	}
 
 	// Fetch updates (NOTE: 3 return values)
-	data, status, err := h.Client.FetchUpdatesRaw(params)
+	data, status, err := h.Client.FetchUpdatesRawContext(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  "failed to fetch updates",
@@ -338,7 +486,12 @@ func (h *TelegramHandler) FetchAndSendToTelegram(c *gin.Context) {
 
 	// Phần còn lại giữ nguyên, chỉ thay trackChatID bằng mainChatID
 	client := http.Client{Timeout: 12 * time.Second}
-	resp, err := client.Get(apiURL)
+	fetchReq, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, apiURL, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid url: %v", err)})
+		return
+	}
+	resp, err := client.Do(fetchReq)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("call API error: %v", err)})
 		return
@@ -359,13 +512,15 @@ func (h *TelegramHandler) FetchAndSendToTelegram(c *gin.Context) {
 		}
 	}
 
-	const maxLen = 3800
-	if len(textToSend) > maxLen {
-		textToSend = textToSend[:maxLen] + "\n... [truncated]"
+	// Body từ API ngoài là dữ liệu không tin cậy: escape rồi đặt trong <pre>.
+	// Body dài sẽ được chia thành nhiều message, thẻ <pre> được đóng/mở lại ở mỗi phần.
+	msg := telegram.SendMessageRequest{
+		ChatID:             mainChatID,
+		Text:               fmt.Sprintf("📡 Data from %s:\n<pre>%s</pre>", telegram.EscapeHTML(apiURL), telegram.EscapeHTML(textToSend)),
+		ParseMode:          telegram.ParseModeHTML,
+		LinkPreviewOptions: &telegram.LinkPreviewOptions{IsDisabled: true},
 	}
-
-	// Sửa trackChatID -> mainChatID ở đây
-	data, status, err := h.Client.SendMessageRaw(mainChatID, fmt.Sprintf("📡 Data from %s:\n%s", apiURL, textToSend))
+	data, status, err := h.Client.SendMessageRequestRawContext(c.Request.Context(), msg)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  fmt.Sprintf("send telegram error: %v", err),
@@ -400,7 +555,7 @@ func (h *TelegramHandler) BanMember(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	data, status, err := h.Client.BanChatMemberRaw(chatID, req.UserID, req.UntilDate)
+	data, status, err := h.Client.BanChatMemberRawContext(c.Request.Context(), chatID, req.UserID, req.UntilDate)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
 		return
@@ -425,7 +580,7 @@ func (h *TelegramHandler) UnbanMember(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	data, status, err := h.Client.UnbanChatMemberRaw(chatID, req.UserID, req.OnlyIfBanned)
+	data, status, err := h.Client.UnbanChatMemberRawContext(c.Request.Context(), chatID, req.UserID, req.OnlyIfBanned)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
 		return
@@ -451,7 +606,7 @@ func (h *TelegramHandler) CreateInviteLink(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	data, status, err := h.Client.CreateChatInviteLinkRaw(chatID, req.Name, req.ExpireDate, req.MemberLimit)
+	data, status, err := h.Client.CreateChatInviteLinkRawContext(c.Request.Context(), chatID, req.Name, req.ExpireDate, req.MemberLimit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
 		return
@@ -469,9 +624,10 @@ func bindAny(c *gin.Context, v interface{}) error {
 }
 
 type MessageActionRequest struct {
-	ChatID    interface{} `json:"chat_id" form:"chat_id" binding:"required"`
-	MessageID int         `json:"message_id" form:"message_id" binding:"required"`
-	Text      string      `json:"text"` // chỉ dùng cho Edit
+	ChatID      interface{}                    `json:"chat_id" form:"chat_id" binding:"required"`
+	MessageID   int                            `json:"message_id" form:"message_id" binding:"required"`
+	Text        string                         `json:"text"`                  // chỉ dùng cho Edit
+	ReplyMarkup *telegram.InlineKeyboardMarkup `json:"reply_markup" form:"-"` // chỉ dùng cho Edit / EditReplyMarkup
 }
 
 func (h *TelegramHandler) PinMessage(c *gin.Context) {
@@ -487,7 +643,7 @@ func (h *TelegramHandler) PinMessage(c *gin.Context) {
 		return
 	}
 
-	data, status, err := h.Client.PinChatMessageRaw(chatID, req.MessageID)
+	data, status, err := h.Client.PinChatMessageRawContext(c.Request.Context(), chatID, req.MessageID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
 		return
@@ -509,7 +665,7 @@ func (h *TelegramHandler) UnpinMessage(c *gin.Context) {
 		return
 	}
 
-	data, status, err := h.Client.UnpinChatMessageRaw(chatID, req.MessageID)
+	data, status, err := h.Client.UnpinChatMessageRawContext(c.Request.Context(), chatID, req.MessageID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
 		return
@@ -535,8 +691,14 @@ func (h *TelegramHandler) EditMessage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "text required for edit"})
 		return
 	}
+	if req.ReplyMarkup != nil {
+		if err := req.ReplyMarkup.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	data, status, err := h.Client.EditMessageTextRaw(chatID, req.MessageID, req.Text)
+	data, status, err := h.Client.EditMessageTextMarkupRawContext(c.Request.Context(), chatID, req.MessageID, req.Text, req.ReplyMarkup)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
 		return
@@ -558,7 +720,36 @@ func (h *TelegramHandler) DeleteMessage(c *gin.Context) {
 		return
 	}
 
-	data, status, err := h.Client.DeleteMessageRaw(chatID, req.MessageID)
+	data, status, err := h.Client.DeleteMessageRawContext(c.Request.Context(), chatID, req.MessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// EditMessageReplyMarkup thay (hoặc xoá nếu không gửi reply_markup) inline keyboard của message
+func (h *TelegramHandler) EditMessageReplyMarkup(c *gin.Context) {
+	var req MessageActionRequest
+	if err := bindAny(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chatID, err := normalizeChatID(req.ChatID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ReplyMarkup != nil {
+		if err := req.ReplyMarkup.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	data, status, err := h.Client.EditMessageReplyMarkupRawContext(c.Request.Context(), chatID, req.MessageID, req.ReplyMarkup)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
 		return
@@ -566,3 +757,199 @@ func (h *TelegramHandler) DeleteMessage(c *gin.Context) {
 
 	c.Data(http.StatusOK, "application/json", data)
 }
+
+func (h *TelegramHandler) AnswerCallbackQuery(c *gin.Context) {
+	var req telegram.AnswerCallbackQueryRequest
+	if err := bindAny(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, status, err := h.Client.AnswerCallbackQueryRawContext(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": status, "body": string(data)})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// ---- SendMediaGroup (album) ----
+type SendMediaGroupRequest struct {
+	ChatID interface{}           `json:"chat_id"`
+	Media  []telegram.InputMedia `json:"media" binding:"required,min=2,max=10"`
+}
+
+// SendMediaGroup gửi album:
+//   - JSON: {"chat_id": ..., "media": [{"type":"photo","media":"<url|file_id|server path>","caption":"..."}]}
+//   - multipart: nhiều file ở field "files", caption từng file ở field "captions" (lặp lại theo thứ tự),
+//     "type" để ép kiểu (mặc định đoán theo đuôi file), "media" (lặp lại) để thêm URL/file_id.
+//     Album chỉ gửi được khi đã có chat_id và đủ mọi file, nên các file được spool ra thư mục tạm
+//     (readStreamedForm) thay vì chuyển thẳng sang Telegram như sendMultipartMedia.
+func (h *TelegramHandler) SendMediaGroup(c *gin.Context) {
+	ct := c.ContentType()
+
+	var chatIDRaw interface{}
+	var items []telegram.InputMedia
+	switch {
+	case strings.HasPrefix(ct, "application/json"):
+		var req SendMediaGroupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		chatIDRaw = req.ChatID
+		items = req.Media
+	case strings.HasPrefix(ct, "multipart/form-data"):
+		// album tối đa 10 file, mỗi file theo giới hạn của loại media lớn nhất
+		form, status, err := readStreamedForm(c, 10*h.Client.MaxUploadSize("video")+multipartOverhead, "files")
+		if err != nil {
+			c.JSON(status, gin.H{"error": "invalid multipart form", "detail": err.Error()})
+			return
+		}
+		defer form.cleanup()
+		chatIDRaw = form.value("chat_id")
+		forceType := form.value("type")
+		captions := form.values["captions"]
+		captionAt := func(i int) string {
+			if i < len(captions) {
+				return captions[i]
+			}
+			if i == 0 {
+				return form.value("caption")
+			}
+			return ""
+		}
+
+		for _, f := range form.files["files"] {
+			mediaType := forceType
+			if mediaType == "" {
+				mediaType = telegram.MediaTypeFromFilename(f.name)
+			}
+			items = append(items, telegram.InputMedia{Type: mediaType, Media: f.path, Caption: captionAt(len(items))})
+		}
+		for _, m := range form.values["media"] {
+			mediaType := forceType
+			if mediaType == "" {
+				mediaType = telegram.MediaTypeFromFilename(m)
+			}
+			items = append(items, telegram.InputMedia{Type: mediaType, Media: m, Caption: captionAt(len(items))})
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported content-type", "type": ct})
+		return
+	}
+
+	chatID, err := normalizeChatID(chatIDRaw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := telegram.ContextWithProgress(c.Request.Context(), logUploadProgress("sendMediaGroup", "album"))
+	data, status, err := h.Client.SendMediaGroupRawContext(ctx, chatID, items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "telegram error", "detail": err.Error(), "status": status, "body": string(data)})
+		return
+	}
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// streamedForm là multipart đã đọc theo từng part: field text giữ trong RAM (có giới hạn),
+// file được spool ra thư mục tạm, cleanup xoá đi sau khi gửi xong
+type streamedForm struct {
+	values map[string][]string
+	files  map[string][]streamedFile
+	dir    string
+}
+
+type streamedFile struct {
+	name string // tên file client gửi
+	path string // file tạm trên disk (giữ đuôi file để Telegram nhận đúng loại)
+}
+
+func (f *streamedForm) value(key string) string {
+	if v := f.values[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (f *streamedForm) cleanup() {
+	if f.dir != "" {
+		os.RemoveAll(f.dir)
+	}
+}
+
+// readStreamedForm đọc multipart request dưới MaxBytesReader thay vì c.MultipartForm
+// (buffer tới 32 MB trong RAM). Chỉ part ở fileFields được nhận là file, part file khác bị bỏ qua.
+// Trả về HTTP status phù hợp khi lỗi (413 nếu vượt maxBody).
+// Mọi file đều đi qua disk (không stream thẳng sang Telegram): dùng cho SendMediaGroup, SetWebhook.
+func readStreamedForm(c *gin.Context, maxBody int64, fileFields ...string) (*streamedForm, int, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	form := &streamedForm{values: map[string][]string{}, files: map[string][]streamedFile{}}
+	var logged []map[string]any
+	fail := func(err error) (*streamedForm, int, error) {
+		form.cleanup()
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, http.StatusRequestEntityTooLarge, err
+		}
+		return nil, http.StatusBadRequest, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		name := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize))
+			if err != nil {
+				return fail(err)
+			}
+			form.values[name] = append(form.values[name], string(value))
+			continue
+		}
+		if !slices.Contains(fileFields, name) {
+			continue
+		}
+
+		if form.dir == "" {
+			if form.dir, err = os.MkdirTemp("", "tg-upload-*"); err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+		}
+		filename := filepath.Base(part.FileName())
+		dst, err := os.CreateTemp(form.dir, "*"+filepath.Ext(filename))
+		if err != nil {
+			form.cleanup()
+			return nil, http.StatusInternalServerError, err
+		}
+		dst.Close()
+		counter := &countingReader{r: part}
+		if err := spoolToFile(dst.Name(), counter); err != nil {
+			return fail(err)
+		}
+		form.files[name] = append(form.files[name], streamedFile{name: filename, path: dst.Name()})
+		logged = append(logged, map[string]any{
+			"field":        name,
+			"filename":     filename,
+			"content_type": part.Header.Get("Content-Type"),
+			"size":         counter,
+		})
+	}
+	if len(logged) > 0 {
+		c.Set(middleware.StreamedFilesKey, logged)
+	}
+	return form, 0, nil
+}
@@ -0,0 +1,44 @@
+package v1handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dnk.com/hoc-golang/telegram"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleUpdateSyncErrorNotRedelivered(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	seen, err := telegram.NewSeenUpdates("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewTelegramHandler(telegram.NewTelegramClient("TOKEN", telegram.WithoutRateLimit()))
+	h.Seen = seen
+	calls := 0
+	h.Dispatcher.OnFallback(func(ctx context.Context, u *telegram.Update) error {
+		calls++
+		return errors.New("forbidden: bot was blocked by the user")
+	})
+	r := gin.New()
+	r.POST("/webhook", h.HandleUpdate)
+
+	// Telegram gửi lại cùng update: handler lỗi vẫn chỉ chạy một lần
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"update_id":7,"message":{"message_id":1,"chat":{"id":1},"text":"hi"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("delivery %d: status = %d, want 200; body %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+}
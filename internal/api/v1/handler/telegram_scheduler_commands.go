@@ -0,0 +1,213 @@
+package v1handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"dnk.com/hoc-golang/middleware"
+	"dnk.com/hoc-golang/telegram"
+)
+
+// schedulerCommands là các lệnh chỉ admin scheduler mới thấy trong menu "/"
+var schedulerCommands = map[string]bool{
+	"scheduler_status": true, "scheduler_start": true, "scheduler_stop": true, "scheduler_run_now": true,
+}
+
+// SchedulerAccess quy định ai được dùng /scheduler_*; rỗng cả hai thì không ai dùng được
+type SchedulerAccess struct {
+	AdminIDs   []int64 // user được dùng ở mọi chat
+	AdminChats []int64 // admin của các chat này được dùng trong chính chat đó
+}
+
+// RegisterSchedulerCommands đăng ký /scheduler_status, /scheduler_start, /scheduler_stop,
+// /scheduler_run_now cho những người trong access; scheduler nil (thiếu cấu hình) thì lệnh
+// vẫn có nhưng báo chưa khởi tạo.
+func (h *TelegramHandler) RegisterSchedulerCommands(scheduler *Scheduler, access SchedulerAccess) {
+	h.Scheduler = scheduler
+	h.schedulerAccess = access
+	if len(access.AdminIDs) == 0 && len(access.AdminChats) == 0 {
+		middleware.LogTelegramError("⚠️ No scheduler admins configured, /scheduler_* is disabled for everyone", nil, nil)
+	}
+	guard := h.requireSchedulerAdmin(access)
+	h.Dispatcher.OnCommand("scheduler_status", "Trạng thái scheduler", h.schedulerStatusCommand, guard)
+	h.Dispatcher.OnCommand("scheduler_start", "Bật scheduler", h.schedulerStartCommand, guard)
+	h.Dispatcher.OnCommand("scheduler_stop", "Tắt scheduler", h.schedulerStopCommand, guard)
+	h.Dispatcher.OnCommand("scheduler_run_now", "Chạy ngay mọi job hoặc một job: /scheduler_run_now <job>", h.schedulerRunNowCommand, guard)
+}
+
+// requireSchedulerAdmin cho qua user trong AdminIDs, hoặc admin của chat đang nhắn nếu chat đó
+// nằm trong AdminChats; mặc định từ chối. Người bị từ chối nhận câu trả lời thay vì bị bỏ qua
+// im lặng như AuthMiddleware.
+func (h *TelegramHandler) requireSchedulerAdmin(access SchedulerAccess) telegram.Middleware {
+	allowedUsers := make(map[int64]bool, len(access.AdminIDs))
+	for _, id := range access.AdminIDs {
+		allowedUsers[id] = true
+	}
+	adminChats := make(map[int64]bool, len(access.AdminChats))
+	for _, id := range access.AdminChats {
+		adminChats[id] = true
+	}
+	return func(next telegram.UpdateHandlerFunc) telegram.UpdateHandlerFunc {
+		return func(ctx context.Context, u *telegram.Update) error {
+			sender, chat := u.Sender(), u.Chat()
+			if sender == nil || chat == nil {
+				return nil
+			}
+			userID := int64(sender.ID)
+			if allowedUsers[userID] {
+				return next(ctx, u)
+			}
+			if adminChats[chat.ID] {
+				member, err := h.Client.GetChatMemberContext(ctx, chat.ID, userID)
+				if err != nil {
+					middleware.LogTelegramError("⚠️ getChatMember failed", err, map[string]interface{}{"chat_id": chat.ID, "user_id": userID})
+				} else if member.IsAdmin() {
+					return next(ctx, u)
+				}
+			}
+			middleware.LogTelegramInfo("🚫 Scheduler command rejected", map[string]interface{}{"chat_id": chat.ID, "user_id": userID})
+			_, _, err := h.Client.SendMessageRawContext(ctx, chat.ID, "⛔ Bạn không có quyền điều khiển scheduler.")
+			return err
+		}
+	}
+}
+
+// PublishCommands đăng ký menu "/" với Telegram: scope default không có lệnh /scheduler_*,
+// chỉ admin của AdminChats và chat riêng với AdminIDs mới thấy thêm các lệnh đó
+func (h *TelegramHandler) PublishCommands(ctx context.Context) error {
+	all := h.Dispatcher.Commands()
+	public := make([]telegram.CommandInfo, 0, len(all))
+	for _, cmd := range all {
+		if !schedulerCommands[cmd.Command] {
+			public = append(public, cmd)
+		}
+	}
+	if _, _, err := h.Client.SetMyCommandsContext(ctx, public, nil); err != nil {
+		return err
+	}
+	// Scope hẹp thay hẳn scope default nên phải gửi đủ mọi lệnh
+	var errs []error
+	for _, chatID := range h.schedulerAccess.AdminChats {
+		scope := &telegram.BotCommandScope{Type: "chat_administrators", ChatID: chatID}
+		if _, _, err := h.Client.SetMyCommandsContext(ctx, all, scope); err != nil {
+			errs = append(errs, fmt.Errorf("chat_administrators %d: %w", chatID, err))
+		}
+	}
+	for _, userID := range h.schedulerAccess.AdminIDs {
+		scope := &telegram.BotCommandScope{Type: "chat", ChatID: userID}
+		if _, _, err := h.Client.SetMyCommandsContext(ctx, all, scope); err != nil {
+			errs = append(errs, fmt.Errorf("chat %d: %w", userID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *TelegramHandler) schedulerStatusCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	if h.Scheduler == nil {
+		return h.reply(ctx, msg, "⚠️ Scheduler chưa được khởi tạo (thiếu TELEGRAM_BOT_TOKEN).")
+	}
+	st := h.Scheduler.Status()
+	var b strings.Builder
+	if st.Running {
+		b.WriteString("🟢 Scheduler đang chạy\n")
+	} else {
+		b.WriteString("⚪️ Scheduler đang tắt\n")
+	}
+	if len(st.Jobs) == 0 {
+		b.WriteString("Chưa có job nào.")
+	}
+	for _, job := range st.Jobs {
+		icon := "⏸"
+		switch {
+		case job.Busy:
+			icon = "⏳"
+		case job.Active:
+			icon = "▶️"
+		}
+		fmt.Fprintf(&b, "\n%s %s — %s (%s)\n", icon, job.Name, job.Action, job.Schedule)
+		fmt.Fprintf(&b, "   Đã chạy: %d", job.Runs)
+		if job.RunLimit > 0 {
+			fmt.Fprintf(&b, "/%d", job.RunLimit)
+		}
+		fmt.Fprintf(&b, " · gần nhất: %s · tiếp theo: %s\n", formatStatusTime(job.LastRunAt), formatStatusTime(job.NextRunAt))
+		if job.LastError != "" {
+			fmt.Fprintf(&b, "   ❌ %s\n", job.LastError)
+		}
+	}
+	if len(st.Pending) > 0 {
+		fmt.Fprintf(&b, "\n⏲ Bước chain đang chờ: %d (sớm nhất %s)\n", len(st.Pending), formatStatusTime(st.Pending[0].DueAt))
+	}
+	return h.reply(ctx, msg, b.String())
+}
+
+func (h *TelegramHandler) schedulerStartCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	if h.Scheduler == nil {
+		return h.reply(ctx, msg, "⚠️ Scheduler chưa được khởi tạo.")
+	}
+	if err := h.Scheduler.Start(); err != nil {
+		return h.reply(ctx, msg, "⚠️ "+err.Error())
+	}
+	middleware.LogTelegramInfo("✅ Scheduler started by bot command", senderFields(msg))
+	return h.reply(ctx, msg, "✅ Đã bật scheduler.")
+}
+
+func (h *TelegramHandler) schedulerStopCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	if h.Scheduler == nil || !h.Scheduler.IsRunning() {
+		return h.reply(ctx, msg, "⚠️ Scheduler không chạy.")
+	}
+	// Stop chờ lượt đang chạy dừng hẳn (có thể đang upload), không giữ worker xử lý update
+	go h.Scheduler.Stop()
+	middleware.LogTelegramInfo("🛑 Scheduler stopped by bot command", senderFields(msg))
+	return h.reply(ctx, msg, "🛑 Đang tắt scheduler.")
+}
+
+func (h *TelegramHandler) schedulerRunNowCommand(ctx context.Context, msg *telegram.Message, cmd telegram.Command) error {
+	if h.Scheduler == nil {
+		return h.reply(ctx, msg, "⚠️ Scheduler chưa được khởi tạo.")
+	}
+	// "/scheduler_run_now <job>" chạy một job, không có tham số thì chạy mọi job đang bật
+	if len(cmd.Args) > 0 {
+		name := cmd.Args[0]
+		if err := h.Scheduler.RunJobNow(name); err != nil {
+			switch {
+			case errors.Is(err, ErrJobNotFound):
+				return h.reply(ctx, msg, fmt.Sprintf("⚠️ Không có job %q.", name))
+			case errors.Is(err, ErrJobBusy):
+				return h.reply(ctx, msg, fmt.Sprintf("⏳ Job %s đang chạy, thử lại sau.", name))
+			}
+			return err
+		}
+		middleware.LogTelegramInfo("▶️ Scheduler job triggered by bot command", senderFields(msg))
+		return h.reply(ctx, msg, fmt.Sprintf("▶️ Đã bắt đầu chạy job %s.", name))
+	}
+	started := h.Scheduler.RunNow()
+	if len(started) == 0 {
+		return h.reply(ctx, msg, "⏳ Không có job nào được chạy (chưa có job bật hoặc tất cả đang bận).")
+	}
+	middleware.LogTelegramInfo("▶️ Scheduler run triggered by bot command", senderFields(msg))
+	return h.reply(ctx, msg, "▶️ Đã bắt đầu chạy: "+strings.Join(started, ", "))
+}
+
+func (h *TelegramHandler) reply(ctx context.Context, msg *telegram.Message, text string) error {
+	_, _, err := h.Client.SendMessageRawContext(ctx, msg.Chat.ID, text)
+	return err
+}
+
+func senderFields(msg *telegram.Message) map[string]interface{} {
+	fields := map[string]interface{}{"chat_id": msg.Chat.ID}
+	if msg.From != nil {
+		fields["user_id"] = msg.From.ID
+		fields["username"] = msg.From.Username
+	}
+	return fields
+}
+
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return "—"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,105 @@
+package v1handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dnk.com/hoc-golang/telegram"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// SetWebhook đăng ký webhook với Telegram. secret_token bỏ trống thì dùng WebhookSecret;
+// secret khác WebhookSecret bị từ chối vì route webhook sẽ trả 401 cho mọi update.
+//   - JSON: {"url": "...", "secret_token": "...", "allowed_updates": ["message"], "max_connections": 40, "drop_pending_updates": true}
+//   - multipart: các field như trên (allowed_updates lặp lại), file chứng chỉ tự ký ở field "certificate"
+//     (spool ra thư mục tạm qua readStreamedForm, không stream thẳng sang Telegram)
+func (h *TelegramHandler) SetWebhook(c *gin.Context) {
+	var req telegram.SetWebhookParams
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		form, status, err := readStreamedForm(c, h.Client.MaxUploadSize("certificate")+multipartOverhead, "certificate")
+		if err != nil {
+			c.JSON(status, gin.H{"error": "invalid multipart form", "detail": err.Error()})
+			return
+		}
+		defer form.cleanup()
+		if req, err = webhookParamsFromForm(form); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err := bindAny(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.SecretToken == "" {
+		req.SecretToken = h.WebhookSecret
+	} else if req.SecretToken != h.WebhookSecret {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "secret_token must match TELEGRAM_WEBHOOK_SECRET, leave it empty to use the configured secret"})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, status, err := h.Client.SetWebhookRawContext(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "telegram error", "detail": err.Error(), "status": status, "body": string(data)})
+		return
+	}
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// webhookParamsFromForm dựng SetWebhookParams từ multipart đã stream và kiểm tra như khi bind
+func webhookParamsFromForm(form *streamedForm) (telegram.SetWebhookParams, error) {
+	req := telegram.SetWebhookParams{
+		URL:            form.value("url"),
+		SecretToken:    form.value("secret_token"),
+		AllowedUpdates: form.values["allowed_updates"],
+		IPAddress:      form.value("ip_address"),
+	}
+	if v := form.value("max_connections"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return req, fmt.Errorf("max_connections must be a number")
+		}
+		req.MaxConnections = n
+	}
+	if v := form.value("drop_pending_updates"); v != "" {
+		drop, err := strconv.ParseBool(v)
+		if err != nil {
+			return req, fmt.Errorf("drop_pending_updates must be true or false")
+		}
+		req.DropPendingUpdates = drop
+	}
+	if files := form.files["certificate"]; len(files) > 0 {
+		req.Certificate = files[0].path
+	}
+	return req, binding.Validator.ValidateStruct(&req)
+}
+
+// DeleteWebhook gỡ webhook, ?drop_pending_updates=true để bỏ các update đang chờ
+func (h *TelegramHandler) DeleteWebhook(c *gin.Context) {
+	drop, _ := strconv.ParseBool(c.Query("drop_pending_updates"))
+
+	data, status, err := h.Client.DeleteWebhookRawContext(c.Request.Context(), drop)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "telegram error", "detail": err.Error(), "status": status, "body": string(data)})
+		return
+	}
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// GetWebhookInfo trả về trạng thái webhook hiện tại
+func (h *TelegramHandler) GetWebhookInfo(c *gin.Context) {
+	info, err := h.Client.GetWebhookInfoContext(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "telegram error", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
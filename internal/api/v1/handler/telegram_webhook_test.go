@@ -0,0 +1,59 @@
+package v1handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dnk.com/hoc-golang/telegram"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetWebhookSecretToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		body       string
+		want       int
+		wantSecret string // secret_token gửi tới Telegram
+	}{
+		{name: "empty uses configured secret", body: `{"url":"https://example.com/hook"}`, want: http.StatusOK, wantSecret: "s3cret"},
+		{name: "same secret", body: `{"url":"https://example.com/hook","secret_token":"s3cret"}`, want: http.StatusOK, wantSecret: "s3cret"},
+		{name: "other secret rejected", body: `{"url":"https://example.com/hook","secret_token":"other"}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+			}))
+			defer srv.Close()
+
+			h := NewTelegramHandler(telegram.NewTelegramClient("TOKEN", telegram.WithBaseURL(srv.URL), telegram.WithoutRateLimit()))
+			h.WebhookSecret = "s3cret"
+			r := gin.New()
+			r.POST("/webhook/set", h.SetWebhook)
+			req := httptest.NewRequest(http.MethodPost, "/webhook/set", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.wantSecret == "" {
+				if sent != nil {
+					t.Fatalf("setWebhook called with %v", sent)
+				}
+				return
+			}
+			if sent["secret_token"] != tt.wantSecret {
+				t.Fatalf("secret_token sent = %v, want %q", sent["secret_token"], tt.wantSecret)
+			}
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	v1handler "dnk.com/hoc-golang/internal/api/v1/handler"
 	v2handler "dnk.com/hoc-golang/internal/api/v2/handler"
@@ -57,77 +61,227 @@ func main() {
 	if err := utils.RegisterValidators(); err != nil {
 		panic(err)
 	}
+	if err := v1handler.RegisterJobValidators(); err != nil {
+		panic(err)
+	}
 
-	// ---------- Scheduler setup ----------
-	var scheduler *v1handler.Scheduler
-	if botToken != "" && chatID != 0 {
-		tgClient := telegram.NewTelegramClient(botToken)
-
-		// Interval minutes có thể test nhanh 0.1 phút (~6s)
-		intervalMinutes := 5.0
-		if v := os.Getenv("SCHEDULER_INTERVAL"); v != "" {
-			if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 {
-				intervalMinutes = n
-			}
-		}
+	// ---------- Telegram client (dùng chung cho handler + scheduler) ----------
+	tgClient := telegram.NewTelegramClient(botToken, telegramOptionsFromEnv()...)
 
-		runCount := 0
-		if v := os.Getenv("SCHEDULER_RUNS"); v != "" {
-			if n, err := strconv.Atoi(v); err == nil {
-				runCount = n
-			}
+	// TELEGRAM_UPDATE_MODE: "webhook" (mặc định) hoặc "polling" (long polling, không cần URL public)
+	updateMode := os.Getenv("TELEGRAM_UPDATE_MODE")
+	if updateMode == "" {
+		updateMode = "webhook"
+	}
+	if updateMode != "webhook" && updateMode != "polling" {
+		log.Fatalf("❌ Invalid TELEGRAM_UPDATE_MODE %q (webhook|polling)", updateMode)
+	}
+
+	// ---------- Tự đăng ký webhook nếu có TELEGRAM_WEBHOOK_URL ----------
+	if params, ok := webhookParamsFromEnv(); ok && botToken != "" && updateMode == "webhook" {
+		if params.SecretToken == "" {
+			log.Println("⚠ TELEGRAM_WEBHOOK_SECRET not set — the webhook route will reject Telegram's requests")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if _, _, err := tgClient.SetWebhookRawContext(ctx, params); err != nil {
+			log.Printf("❌ Cannot register webhook %s: %v", params.URL, err)
+		} else {
+			log.Printf("✅ Webhook registered: %s", params.URL)
 		}
+		cancel()
+	}
 
-		retryCount := 2
-		retryDelaySec := 5
-		logFilePath := "logs/api_check.log"
-
-		// Tạo Scheduler
-		s, err := v1handler.NewScheduler(
-			strconv.FormatInt(chatID, 10),
-			tgClient,
-			intervalMinutes, // float64
-			runCount,
-			retryCount,
-			retryDelaySec,
-			logFilePath,
-		)
+	// ---------- Scheduler setup ----------
+	var scheduler *v1handler.Scheduler
+	if botToken != "" {
+		s, err := v1handler.NewScheduler(tgClient, "logs/api_check.log")
 		if err != nil {
 			log.Fatalf("❌ Failed to create scheduler: %v", err)
 		}
 		scheduler = s
 
-		log.Println("✅ Scheduler initialized (not started yet)")
+		// SCHEDULER_CATCH_UP: skip (mặc định), once hoặc all cho lượt bị lỡ khi server tắt
+		if v := os.Getenv("SCHEDULER_CATCH_UP"); v != "" {
+			if err := scheduler.SetDefaultCatchUp(v); err != nil {
+				log.Fatalf("❌ Invalid SCHEDULER_CATCH_UP: %v", err)
+			}
+		}
+
+		// SCHEDULER_STORE_FILE: nơi lưu job và số lượt đã chạy ("off" = không lưu).
+		// Job từ env/SCHEDULER_JOBS_FILE chỉ được thêm ở lần chạy đầu (chưa có file); sau đó file
+		// là nguồn duy nhất, kể cả khi mọi job đã bị xoá. Xoá file để nạp lại cấu hình từ env.
+		var stored v1handler.StoreInfo
+		storePath := os.Getenv("SCHEDULER_STORE_FILE")
+		if storePath == "" {
+			storePath = "data/scheduler_jobs.json"
+		}
+		if storePath != "off" {
+			if stored, err = scheduler.UseStore(v1handler.NewFileJobStore(storePath)); err != nil {
+				log.Fatalf("❌ Cannot load scheduler store: %v", err)
+			}
+		}
+		if !stored.Found {
+			jobs, err := schedulerJobsFromEnv(chatID)
+			if err != nil {
+				log.Fatalf("❌ Invalid scheduler jobs: %v", err)
+			}
+			for _, job := range jobs {
+				if err := scheduler.AddJob(job); err != nil {
+					log.Fatalf("❌ Invalid scheduler job: %v", err)
+				}
+			}
+		} else {
+			log.Printf("✅ Loaded %d scheduler job(s) from %s", stored.Jobs, storePath)
+			log.Printf("⚠ Scheduler job config from env (SCHEDULER_JOBS_FILE, SCHEDULER_CRON, SCHEDULER_INTERVAL, SCHEDULER_RUNS...) is ignored because %s exists; delete it to re-seed", storePath)
+		}
+		jobs := scheduler.Jobs()
+		log.Printf("✅ Scheduler initialized with %d job(s) (not started yet)", len(jobs))
 
 		// In cấu hình scheduler đẹp
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintf(w, "Interval (minutes)\tRuns\tRetry Count\tRetry Delay (s)\n")
-		fmt.Fprintf(w, "%.2f\t%d\t%d\t%d\n", intervalMinutes, runCount, retryCount, retryDelaySec)
+		fmt.Fprintf(w, "Job\tAction\tSchedule\tChats\tRuns\tRetry Count\tRetry Delay (s)\tEnabled\n")
+		for _, job := range jobs {
+			runs := strconv.Itoa(job.Runs)
+			if job.RunLimit > 0 {
+				runs += "/" + strconv.Itoa(job.RunLimit)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%s\t%d\t%d\t%t\n", job.Name, job.Action, job.Schedule, job.ChatIDs,
+				runs, job.Retry.Attempts, job.Retry.DelaySec, job.Enabled)
+		}
 		w.Flush()
 
 		// Run scheduler ngay khi startup nếu bật SCHEDULER_RUN_IMMEDIATE=1
-		if v := os.Getenv("SCHEDULER_RUN_IMMEDIATE"); v == "1" {
+		// hoặc scheduler đang chạy trước khi process tắt (chưa bị Stop)
+		if os.Getenv("SCHEDULER_RUN_IMMEDIATE") == "1" || stored.Running {
 			if err := scheduler.Start(); err != nil {
 				log.Printf("❌ Scheduler failed to start immediately: %v", err)
+			} else if stored.Running {
+				log.Println("✅ Scheduler resumed: it was running before restart")
 			} else {
 				log.Println("✅ Scheduler started immediately at startup")
 			}
 		}
 	} else {
-		log.Println("⚠ Scheduler not initialized — missing TELEGRAM_BOT_TOKEN or TELEGRAM_CHAT_ID")
+		log.Println("⚠ Scheduler not initialized — missing TELEGRAM_BOT_TOKEN")
 	}
 
 	// ---------- Gin Router ----------
 	r := gin.Default()
-	telegramHandler := v1handler.NewTelegramHandler(botToken)
+	if err := middleware.SetTrustedProxies(r); err != nil {
+		log.Fatalf("❌ Invalid TRUSTED_PROXIES: %v", err)
+	}
+	telegramHandler := v1handler.NewTelegramHandler(tgClient)
+	telegramHandler.WebhookSecret = os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	telegramHandler.Dispatcher.Use(telegram.RecoveryMiddleware(), telegram.LoggingMiddleware())
+	if ids := telegramIDsFromEnv("TELEGRAM_ALLOWED_USER_IDS"); len(ids) > 0 {
+		telegramHandler.Dispatcher.Use(telegram.AuthMiddleware(telegram.AllowUserIDs(ids...)))
+	}
+	telegramHandler.RegisterDefaultCommands()
 
-	r.Use(
-		middleware.LoggerMiddleware(),
+	// ---------- Hội thoại nhiều bước ----------
+	if store, err := conversationStoreFromEnv(); err != nil {
+		log.Printf("⚠ conversations disabled: %v", err)
+	} else {
+		timeout := 5 * time.Minute
+		if v := os.Getenv("TELEGRAM_CONVERSATION_TIMEOUT"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				timeout = time.Duration(n) * time.Second
+			}
+		}
+		conversations := telegram.NewConversationManager(store, timeout)
+		// Sau auth: user bị chặn không được vào hội thoại
+		telegramHandler.Dispatcher.Use(conversations.Middleware())
+		telegramHandler.Conversations = conversations
+		telegramHandler.RegisterConversations()
+
+		sweepCtx, stopSweep := context.WithCancel(context.Background())
+		defer stopSweep()
+		go conversations.RunSweeper(sweepCtx, 30*time.Second)
+	}
+
+	// ---------- Webhook: chống trùng update_id + xử lý nền ----------
+	seenPath := os.Getenv("TELEGRAM_SEEN_UPDATES_FILE")
+	if seenPath == "" {
+		seenPath = "data/telegram_seen_updates.json"
+	}
+	if seen, err := telegram.NewSeenUpdates(seenPath, telegram.DefaultSeenUpdatesLimit); err != nil {
+		log.Printf("⚠ update de-duplication disabled: %v", err)
+	} else {
+		telegramHandler.Seen = seen
+		defer seen.Close()
+	}
+	workers, queueSize := 4, 100
+	if v := os.Getenv("TELEGRAM_UPDATE_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			workers = n
+		}
+	}
+	if v := os.Getenv("TELEGRAM_UPDATE_QUEUE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			queueSize = n
+		}
+	}
+	// TELEGRAM_UPDATE_WORKERS=0: xử lý đồng bộ trong request webhook
+	if workers > 0 {
+		telegramHandler.Updates = telegram.NewUpdateWorkerPool(telegramHandler.ProcessUpdate, workers, queueSize)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_ = telegramHandler.Updates.Stop(ctx)
+		}()
+	}
+	// TELEGRAM_APPROVER_IDS: user được bấm nút duyệt/từ chối ở mọi chat; ngoài ra chỉ admin của chat đó
+	telegramHandler.RegisterApprovalCallbacks(telegramIDsFromEnv("TELEGRAM_APPROVER_IDS")...)
+	// TELEGRAM_SCHEDULER_ADMIN_IDS: user được dùng /scheduler_* ở mọi chat;
+	// TELEGRAM_SCHEDULER_ADMIN_CHATS: admin của các chat này được dùng trong chat đó. Trống cả hai = không ai dùng được
+	telegramHandler.RegisterSchedulerCommands(scheduler, v1handler.SchedulerAccess{
+		AdminIDs:   telegramIDsFromEnv("TELEGRAM_SCHEDULER_ADMIN_IDS"),
+		AdminChats: telegramIDsFromEnv("TELEGRAM_SCHEDULER_ADMIN_CHATS"),
+	})
+	if botToken != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if me, err := tgClient.GetMeContext(ctx); err != nil {
+			log.Printf("⚠ getMe failed, /cmd@bot in groups will not be filtered: %v", err)
+		} else {
+			telegramHandler.Dispatcher.SetBotUsername(me.Username)
+		}
+		// Hiện các lệnh đã đăng ký trong menu "/" của client; lệnh scheduler chỉ hiện cho admin
+		if err := telegramHandler.PublishCommands(ctx); err != nil {
+			log.Printf("⚠ setMyCommands failed: %v", err)
+		}
+		cancel()
+	}
+
+	// ---------- Long polling ----------
+	if updateMode == "polling" && botToken != "" {
+		poller := telegram.NewPoller(tgClient, telegramHandler.ProcessUpdate, pollerOptionsFromEnv())
+		if err := poller.Start(); err != nil {
+			log.Fatalf("❌ Failed to start poller: %v", err)
+		}
+		defer poller.Stop()
+		// Poller đã đọc update, GetUpdates của scheduler sẽ tranh offset (409 Conflict)
+		if scheduler != nil {
+			if names := scheduler.DisableJobsWithAction(v1handler.ActionGetUpdates); len(names) > 0 {
+				log.Printf("⚠ Disabled scheduler job(s) %v: polling already reads updates", names)
+			}
+		}
+		log.Println("✅ Telegram long polling started")
+	}
+
+	r.Use(middleware.LoggerMiddleware())
+
+	// Webhook: Telegram không gửi X-API-Key nên xác thực bằng secret token (+ IP) thay thế,
+	// thiếu secret thì quay về X-API-Key; không qua rate limit theo IP vì Telegram có thể gửi dồn nhiều update
+	webhook := r.Group("/api/v1/telegram/webhook", middleware.TelegramWebhookMiddleware())
+	webhook.POST("", telegramHandler.HandleUpdate)
+
+	// Các route còn lại yêu cầu X-API-Key
+	protected := r.Group("",
 		middleware.ApiKeyMiddleware(),
 		middleware.RateLimitingMiddleware(),
 	)
 
-	v1 := r.Group("/api/v1")
+	v1 := protected.Group("/api/v1")
 	{
 		telegramGroup := v1.Group("/telegram")
 
@@ -135,17 +289,23 @@ func main() {
 		telegramGroup.POST("/send", telegramHandler.SendMessage)
 		telegramGroup.GET("/updates", telegramHandler.GetUpdates)
 		telegramGroup.GET("/fetch-send", telegramHandler.FetchAndSendToTelegram)
-		telegramGroup.POST("/webhook", telegramHandler.HandleUpdate)
+		telegramGroup.POST("/webhook/set", telegramHandler.SetWebhook)
+		telegramGroup.POST("/webhook/delete", telegramHandler.DeleteWebhook)
+		telegramGroup.GET("/webhook/info", telegramHandler.GetWebhookInfo)
 		telegramGroup.POST("/sendPhoto", telegramHandler.SendPhoto)
 		telegramGroup.POST("/sendAudio", telegramHandler.SendAudio)
 		telegramGroup.POST("/sendDocument", telegramHandler.SendDocument)
 		telegramGroup.POST("/sendVideo", telegramHandler.SendVideo)
 		telegramGroup.POST("/sendAnimation", telegramHandler.SendAnimation)
 		telegramGroup.POST("/sendVoice", telegramHandler.SendVoice)
+		telegramGroup.POST("/sendMediaGroup", telegramHandler.SendMediaGroup)
 		telegramGroup.POST("/pinMessage", telegramHandler.PinMessage)
 		telegramGroup.POST("/unpinMessage", telegramHandler.UnpinMessage)
 		telegramGroup.POST("/editMessage", telegramHandler.EditMessage)
 		telegramGroup.POST("/deleteMessage", telegramHandler.DeleteMessage)
+		telegramGroup.POST("/editMessageReplyMarkup", telegramHandler.EditMessageReplyMarkup)
+		telegramGroup.POST("/answerCallbackQuery", telegramHandler.AnswerCallbackQuery)
+		telegramGroup.GET("/files/:file_id", telegramHandler.GetFile)
 
 		// Member management
 		telegramGroup.POST("/banMember", telegramHandler.BanMember)
@@ -158,7 +318,7 @@ func main() {
 				c.JSON(500, gin.H{"error": "TELEGRAM_BOT_TOKEN or TELEGRAM_CHAT_ID not configured"})
 				return
 			}
-			_, _, err := telegram.NewTelegramClient(botToken).SendMessageRaw(chatID, "🚀 Test message from server")
+			_, _, err := tgClient.SendMessageRawContext(c.Request.Context(), chatID, "🚀 Test message from server")
 			if err != nil {
 				c.JSON(500, gin.H{"error": err.Error()})
 				return
@@ -194,8 +354,22 @@ func main() {
 		})
 	}
 
+	// ---------- Scheduler jobs ----------
+	jobs := protected.Group("/api/v1/scheduler/jobs")
+	{
+		jobHandler := v1handler.NewSchedulerJobHandler(scheduler)
+		jobs.GET("", jobHandler.ListJobs)
+		jobs.POST("", jobHandler.CreateJob)
+		jobs.GET("/:name", jobHandler.GetJob)
+		jobs.PUT("/:name", jobHandler.UpdateJob)
+		jobs.DELETE("/:name", jobHandler.DeleteJob)
+		jobs.POST("/:name/pause", jobHandler.PauseJob)
+		jobs.POST("/:name/resume", jobHandler.ResumeJob)
+		jobs.POST("/:name/run", jobHandler.RunJobNow)
+	}
+
 	// ---------- API v2 ----------
-	v2 := r.Group("/api/v2")
+	v2 := protected.Group("/api/v2")
 	{
 		user := v2.Group("/users")
 		{
@@ -209,7 +383,7 @@ func main() {
 	}
 
 	// Static files
-	r.StaticFS("/images", gin.Dir("./uploads", false))
+	protected.StaticFS("/images", gin.Dir("./uploads", false))
 
 	// ---------- Start server ----------
 	fmt.Printf("🚀 Server running on port %s\n", port)
@@ -217,3 +391,177 @@ func main() {
 		log.Fatalf("❌ Failed to start server: %v", err)
 	}
 }
+
+// telegramOptionsFromEnv đọc cấu hình Bot API từ env:
+// TELEGRAM_API_BASE_URL, TELEGRAM_HTTP_TIMEOUT (giây), TELEGRAM_RETRY_ATTEMPTS,
+// TELEGRAM_RATE_LIMIT (0 = tắt throttle), TELEGRAM_FILE_ID_CACHE (đường dẫn file, "off" = tắt),
+// TELEGRAM_USER_AGENT, TELEGRAM_PROXY_URL
+func telegramOptionsFromEnv() []telegram.Option {
+	var opts []telegram.Option
+	if v := os.Getenv("TELEGRAM_API_BASE_URL"); v != "" {
+		opts = append(opts, telegram.WithBaseURL(v))
+	}
+	if v := os.Getenv("TELEGRAM_HTTP_TIMEOUT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 {
+			opts = append(opts, telegram.WithTimeout(time.Duration(n*float64(time.Second))))
+		}
+	}
+	if v := os.Getenv("TELEGRAM_RETRY_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			policy := telegram.DefaultRetryPolicy()
+			policy.MaxAttempts = n
+			opts = append(opts, telegram.WithRetryPolicy(policy))
+		}
+	}
+	if os.Getenv("TELEGRAM_RATE_LIMIT") == "0" {
+		opts = append(opts, telegram.WithoutRateLimit())
+	}
+	cachePath := os.Getenv("TELEGRAM_FILE_ID_CACHE")
+	if cachePath == "" {
+		cachePath = "data/telegram_file_ids.json"
+	}
+	if cachePath != "off" {
+		cache, err := telegram.NewFileIDCache(cachePath)
+		if err != nil {
+			log.Printf("⚠ file_id cache disabled: %v", err)
+		} else {
+			opts = append(opts, telegram.WithFileIDCache(cache))
+		}
+	}
+	if v := os.Getenv("TELEGRAM_USER_AGENT"); v != "" {
+		opts = append(opts, telegram.WithUserAgent(v))
+	}
+	if v := os.Getenv("TELEGRAM_PROXY_URL"); v != "" {
+		proxyURL, err := url.Parse(v)
+		if err != nil {
+			log.Fatalf("❌ Invalid TELEGRAM_PROXY_URL: %v", err)
+		}
+		opts = append(opts, telegram.WithProxy(proxyURL))
+	}
+	return opts
+}
+
+// webhookParamsFromEnv đọc cấu hình webhook; ok=false nếu không đặt TELEGRAM_WEBHOOK_URL.
+// TELEGRAM_WEBHOOK_URL (URL public trỏ tới /api/v1/telegram/webhook), TELEGRAM_WEBHOOK_SECRET,
+// TELEGRAM_WEBHOOK_ALLOWED_UPDATES (phân cách bởi dấu phẩy), TELEGRAM_WEBHOOK_MAX_CONNECTIONS,
+// TELEGRAM_WEBHOOK_DROP_PENDING (1 = bỏ update đang chờ), TELEGRAM_WEBHOOK_CERT (file PEM tự ký)
+func webhookParamsFromEnv() (telegram.SetWebhookParams, bool) {
+	params := telegram.SetWebhookParams{
+		URL:                os.Getenv("TELEGRAM_WEBHOOK_URL"),
+		SecretToken:        os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
+		DropPendingUpdates: os.Getenv("TELEGRAM_WEBHOOK_DROP_PENDING") == "1",
+		Certificate:        os.Getenv("TELEGRAM_WEBHOOK_CERT"),
+	}
+	if params.URL == "" {
+		return params, false
+	}
+	params.AllowedUpdates = allowedUpdatesFromEnv()
+	if v := os.Getenv("TELEGRAM_WEBHOOK_MAX_CONNECTIONS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			params.MaxConnections = n
+		}
+	}
+	return params, true
+}
+
+// pollerOptionsFromEnv đọc cấu hình long polling:
+// TELEGRAM_POLLING_TIMEOUT (giây, mặc định 50), TELEGRAM_POLLING_OFFSET_FILE
+// (mặc định data/telegram_offset.json), TELEGRAM_WEBHOOK_ALLOWED_UPDATES dùng chung với webhook
+func pollerOptionsFromEnv() telegram.PollerOptions {
+	opts := telegram.PollerOptions{DeleteWebhook: true}
+	if v := os.Getenv("TELEGRAM_POLLING_TIMEOUT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			opts.Timeout = n
+		}
+	}
+	offsetPath := os.Getenv("TELEGRAM_POLLING_OFFSET_FILE")
+	if offsetPath == "" {
+		offsetPath = "data/telegram_offset.json"
+	}
+	opts.Store = telegram.NewFileOffsetStore(offsetPath)
+	opts.AllowedUpdates = allowedUpdatesFromEnv()
+	return opts
+}
+
+// allowedUpdatesFromEnv đọc TELEGRAM_WEBHOOK_ALLOWED_UPDATES (phân cách bởi dấu phẩy), nil nếu không đặt
+func allowedUpdatesFromEnv() []string {
+	var allowed []string
+	for _, u := range strings.Split(os.Getenv("TELEGRAM_WEBHOOK_ALLOWED_UPDATES"), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			allowed = append(allowed, u)
+		}
+	}
+	return allowed
+}
+
+// conversationStoreFromEnv chọn nơi lưu hội thoại theo TELEGRAM_CONVERSATION_STORE:
+// đường dẫn file JSON (mặc định data/telegram_conversations.json) hoặc "memory"
+func conversationStoreFromEnv() (telegram.ConversationStore, error) {
+	path := os.Getenv("TELEGRAM_CONVERSATION_STORE")
+	if path == "memory" {
+		return telegram.NewMemoryConversationStore(), nil
+	}
+	if path == "" {
+		path = "data/telegram_conversations.json"
+	}
+	return telegram.NewFileConversationStore(path)
+}
+
+// schedulerJobsFromEnv đọc danh sách job:
+//   - SCHEDULER_JOBS_FILE: file JSON chứa mảng job (xem v1handler.Job)
+//   - không có file: các job mặc định gửi vào TELEGRAM_CHAT_ID theo SCHEDULER_CRON
+//     (vd "0 9 * * 1-5", múi giờ SCHEDULER_TIMEZONE) hoặc mỗi SCHEDULER_INTERVAL phút,
+//     mỗi job chạy tối đa SCHEDULER_RUNS lượt (0 = không giới hạn)
+func schedulerJobsFromEnv(chatID int64) ([]v1handler.Job, error) {
+	if path := os.Getenv("SCHEDULER_JOBS_FILE"); path != "" {
+		return v1handler.LoadJobsFile(path)
+	}
+	if chatID == 0 {
+		log.Println("⚠ No default scheduler jobs — missing TELEGRAM_CHAT_ID")
+		return nil, nil
+	}
+
+	// Interval minutes có thể test nhanh 0.1 phút (~6s)
+	intervalMinutes := 5.0
+	if v := os.Getenv("SCHEDULER_INTERVAL"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 {
+			intervalMinutes = n
+		}
+	}
+	schedule := "@every " + time.Duration(intervalMinutes*float64(time.Minute)).String()
+	// Theo chu kỳ thì chạy ngay lượt đầu như trước; theo cron thì chờ tới giờ
+	runOnStart := true
+	if spec := os.Getenv("SCHEDULER_CRON"); spec != "" {
+		schedule, runOnStart = spec, false
+	}
+
+	runLimit := 0
+	if v := os.Getenv("SCHEDULER_RUNS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			runLimit = n
+		}
+	}
+
+	jobs := v1handler.DefaultJobs(chatID, schedule, runOnStart, runLimit, v1handler.JobRetry{Attempts: 2, DelaySec: 5})
+	for i := range jobs {
+		jobs[i].Timezone = os.Getenv("SCHEDULER_TIMEZONE")
+	}
+	return jobs, nil
+}
+
+// telegramIDsFromEnv đọc danh sách Telegram user/chat ID phân cách bởi dấu phẩy, bỏ qua giá trị sai
+func telegramIDsFromEnv(key string) []int64 {
+	var ids []int64
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Printf("⚠ Invalid ID %q in %s", v, key)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
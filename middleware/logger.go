@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -41,35 +42,10 @@ func LoggerMiddleware() gin.HandlerFunc {
 		start := time.Now()
 		contentType := ctx.GetHeader("Content-Type")
 		requestBody := make(map[string]any)
-		var formFiles []map[string]any
-		//multipart/form-data
-		if strings.HasPrefix(contentType, "multipart/form-data") {
-			if err := ctx.Request.ParseMultipartForm(32 << 20); err == nil && ctx.Request.MultipartForm != nil {
-				//for value
-				for key, vals := range ctx.Request.MultipartForm.Value {
-					if len(vals) == 1 {
-						requestBody[key] = vals[0]
-					} else {
-						requestBody[key] = vals
-					}
-				}
-				//for file
-				for field, files := range ctx.Request.MultipartForm.File {
-					for _, f := range files {
-						formFiles = append(formFiles, map[string]any{
-							"field":        field,
-							"filename":     f.Filename,
-							"size":         formatFileSize(f.Size),
-							"content_type": f.Header.Get("Content-Type"),
-						})
-					}
-				}
-				if len(formFiles) > 0 {
-					requestBody["form_files"] = formFiles
-				}
-			}
-
-		} else {
+		//multipart/form-data: không parse trước để handler có thể stream file,
+		// form chỉ được log sau ctx.Next() nếu handler đã tự parse
+		isMultipart := strings.HasPrefix(contentType, "multipart/form-data")
+		if !isMultipart {
 			bodyBytes, err := io.ReadAll(ctx.Request.Body)
 
 			if err != nil {
@@ -102,6 +78,11 @@ func LoggerMiddleware() gin.HandlerFunc {
 		ctx.Writer = customWriter
 		ctx.Next()
 
+		if isMultipart {
+			logMultipartForm(ctx, requestBody)
+		}
+		redactRequestBody(requestBody)
+
 		duration := time.Since(start)
 
 		statusCode := ctx.Writer.Status()
@@ -138,7 +119,7 @@ func LoggerMiddleware() gin.HandlerFunc {
 			Str("remote_addr", ctx.Request.RemoteAddr).
 			Str("request_uri", ctx.Request.RequestURI).
 			Int64("content_length", ctx.Request.ContentLength).
-			Interface("headers", ctx.Request.Header).
+			Interface("headers", redactHeaders(ctx.Request.Header)).
 			Interface("request_body", requestBody).
 			//Interface("uploaded_files", formFiles).
 			Interface("response", responseBodyParsed).
@@ -148,7 +129,74 @@ func LoggerMiddleware() gin.HandlerFunc {
 	}
 }
 
-func formatFileSize(size int64) string {
+// Header và field của body chứa secret, không ghi giá trị thật vào log
+var (
+	redactedHeaders    = []string{"X-Telegram-Bot-Api-Secret-Token"}
+	redactedBodyFields = []string{"secret_token"}
+)
+
+const redactedValue = "[REDACTED]"
+
+// redactHeaders trả về bản sao header đã che các header trong redactedHeaders
+func redactHeaders(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range redactedHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, redactedValue)
+		}
+	}
+	return header
+}
+
+// redactRequestBody che các field trong redactedBodyFields (json, form, multipart)
+func redactRequestBody(body map[string]any) {
+	for _, field := range redactedBodyFields {
+		if _, ok := body[field]; ok {
+			body[field] = redactedValue
+		}
+	}
+}
+
+// StreamedFilesKey: handler stream file thẳng từ request (không qua MultipartForm)
+// ghi danh sách file vào ctx với key này để logger vẫn log được
+const StreamedFilesKey = "streamed_files"
+
+// logMultipartForm ghi field/file của multipart request vào requestBody
+func logMultipartForm(ctx *gin.Context, requestBody map[string]any) {
+	var formFiles []map[string]any
+	if form := ctx.Request.MultipartForm; form != nil {
+		//for value
+		for key, vals := range form.Value {
+			if len(vals) == 1 {
+				requestBody[key] = vals[0]
+			} else {
+				requestBody[key] = vals
+			}
+		}
+		//for file
+		for field, files := range form.File {
+			for _, f := range files {
+				formFiles = append(formFiles, map[string]any{
+					"field":        field,
+					"filename":     f.Filename,
+					"size":         FormatFileSize(f.Size),
+					"content_type": f.Header.Get("Content-Type"),
+				})
+			}
+		}
+	}
+	if streamed, ok := ctx.Get(StreamedFilesKey); ok {
+		if files, ok := streamed.([]map[string]any); ok {
+			formFiles = append(formFiles, files...)
+		}
+	}
+	if len(formFiles) > 0 {
+		requestBody["form_files"] = formFiles
+	}
+}
+
+// FormatFileSize đổi số byte sang dạng dễ đọc (B/KB/MB)
+func FormatFileSize(size int64) string {
 	switch {
 	case size >= 1<<20:
 		return fmt.Sprintf("%.2f MB", float64(size)/(1<<20))
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Telegram-Bot-Api-Secret-Token", "s3cret")
+	header.Set("Content-Type", "application/json")
+	logged := redactHeaders(header)
+	if got := logged.Get("X-Telegram-Bot-Api-Secret-Token"); got != redactedValue {
+		t.Fatalf("logged secret header = %q", got)
+	}
+	if got := header.Get("X-Telegram-Bot-Api-Secret-Token"); got != "s3cret" {
+		t.Fatalf("request header modified: %q", got)
+	}
+	if got := logged.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+
+	body := map[string]any{"url": "https://example.com/hook", "secret_token": "s3cret"}
+	redactRequestBody(body)
+	if body["secret_token"] != redactedValue || body["url"] != "https://example.com/hook" {
+		t.Fatalf("body = %v", body)
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Dải IP Telegram dùng để gọi webhook (https://core.telegram.org/bots/webhooks)
+var telegramWebhookCIDRs = []string{
+	"149.154.160.0/20",
+	"91.108.4.0/22",
+}
+
+// TelegramWebhookMiddleware xác thực request webhook đến từ Telegram thay cho X-API-Key:
+//   - TELEGRAM_WEBHOOK_SECRET: so sánh (constant-time) với header X-Telegram-Bot-Api-Secret-Token,
+//     phải trùng với secret_token đã đăng ký qua setWebhook
+//   - TELEGRAM_WEBHOOK_IP_FILTER=1: chỉ nhận request từ dải IP của Telegram. IP lấy theo
+//     ctx.ClientIP(), nên router phải gọi SetTrustedProxies để X-Forwarded-For giả không qua được
+//
+// Không đặt secret thì fail closed: webhook yêu cầu X-API-Key như các route khác
+// (Telegram không gửi header này nên update thật sẽ bị từ chối cho tới khi đặt secret).
+func TelegramWebhookMiddleware() gin.HandlerFunc {
+	secret := []byte(os.Getenv("TELEGRAM_WEBHOOK_SECRET"))
+	var apiKey gin.HandlerFunc
+	if len(secret) == 0 {
+		log.Println("⚠ TELEGRAM_WEBHOOK_SECRET not set — webhook requires X-API-Key, Telegram updates will be rejected")
+		apiKey = ApiKeyMiddleware()
+	}
+
+	var allowed []*net.IPNet
+	if os.Getenv("TELEGRAM_WEBHOOK_IP_FILTER") == "1" {
+		for _, cidr := range telegramWebhookCIDRs {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				panic(err)
+			}
+			allowed = append(allowed, ipNet)
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		if len(allowed) > 0 && !ipAllowed(ctx.ClientIP(), allowed) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if apiKey != nil {
+			apiKey(ctx)
+			return
+		}
+		got := []byte(ctx.GetHeader("X-Telegram-Bot-Api-Secret-Token"))
+		if subtle.ConstantTimeCompare(got, secret) != 1 {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret token"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// SetTrustedProxies chỉ tin X-Forwarded-For/X-Real-IP từ các proxy trong TRUSTED_PROXIES
+// (IP hoặc CIDR, cách nhau dấu phẩy). Không đặt thì không tin proxy nào: ClientIP là RemoteAddr.
+func SetTrustedProxies(r *gin.Engine) error {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return r.SetTrustedProxies(proxies)
+}
+
+func ipAllowed(ipStr string, allowed []*net.IPNet) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range allowed {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
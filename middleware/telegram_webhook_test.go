@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTelegramWebhookMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		secret  string
+		headers map[string]string
+		want    int
+	}{
+		{name: "no secret configured rejects anonymous", want: http.StatusBadRequest},
+		{name: "no secret configured falls back to api key", headers: map[string]string{"X-API-Key": "k"}, want: http.StatusOK},
+		{name: "no secret configured ignores telegram header", headers: map[string]string{"X-Telegram-Bot-Api-Secret-Token": ""}, want: http.StatusBadRequest},
+		{name: "secret matches", secret: "s3cret", headers: map[string]string{"X-Telegram-Bot-Api-Secret-Token": "s3cret"}, want: http.StatusOK},
+		{name: "secret mismatch", secret: "s3cret", headers: map[string]string{"X-Telegram-Bot-Api-Secret-Token": "nope"}, want: http.StatusUnauthorized},
+		{name: "secret missing header", secret: "s3cret", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TELEGRAM_WEBHOOK_SECRET", tt.secret)
+			t.Setenv("API_KEY", "k")
+			r := gin.New()
+			r.POST("/hook", TelegramWebhookMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodPost, "/hook", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelegramWebhookIPFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		proxies    string
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{name: "telegram ip", remoteAddr: "149.154.167.1:443", want: http.StatusOK},
+		{name: "other ip", remoteAddr: "203.0.113.5:1234", want: http.StatusForbidden},
+		{name: "forged forwarded header", remoteAddr: "203.0.113.5:1234", forwarded: "149.154.167.1", want: http.StatusForbidden},
+		{name: "forwarded by trusted proxy", proxies: "10.0.0.0/8", remoteAddr: "10.0.0.2:1234", forwarded: "149.154.167.1", want: http.StatusOK},
+		{name: "forged behind trusted proxy", proxies: "10.0.0.0/8", remoteAddr: "10.0.0.2:1234", forwarded: "203.0.113.5", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TELEGRAM_WEBHOOK_SECRET", "s3cret")
+			t.Setenv("TELEGRAM_WEBHOOK_IP_FILTER", "1")
+			t.Setenv("TRUSTED_PROXIES", tt.proxies)
+			r := gin.New()
+			if err := SetTrustedProxies(r); err != nil {
+				t.Fatal(err)
+			}
+			r.POST("/hook", TelegramWebhookMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodPost, "/hook", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "s3cret")
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
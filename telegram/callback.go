@@ -0,0 +1,56 @@
+package telegram
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CallbackHandlerFunc xử lý một callback query; arg là phần callback_data sau prefix
+type CallbackHandlerFunc func(ctx context.Context, q *CallbackQuery, arg string) error
+
+type callbackRoute struct {
+	prefix  string
+	handler CallbackHandlerFunc
+}
+
+// CallbackRouter chuyển callback query tới handler theo prefix của callback_data,
+// vd "approve:" nhận mọi callback_data dạng "approve:<id>"
+type CallbackRouter struct {
+	mu     sync.RWMutex
+	routes []callbackRoute
+}
+
+func NewCallbackRouter() *CallbackRouter {
+	return &CallbackRouter{}
+}
+
+// Handle đăng ký handler cho prefix; prefix dài hơn được ưu tiên khi trùng
+func (r *CallbackRouter) Handle(prefix string, fn CallbackHandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, callbackRoute{prefix: prefix, handler: fn})
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return len(r.routes[i].prefix) > len(r.routes[j].prefix)
+	})
+}
+
+// Dispatch gọi handler khớp prefix; trả về false nếu không có handler nào khớp
+func (r *CallbackRouter) Dispatch(ctx context.Context, q *CallbackQuery) (bool, error) {
+	r.mu.RLock()
+	var matched *callbackRoute
+	for i := range r.routes {
+		if strings.HasPrefix(q.Data, r.routes[i].prefix) {
+			route := r.routes[i]
+			matched = &route
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if matched == nil {
+		return false, nil
+	}
+	return true, matched.handler(ctx, q, strings.TrimPrefix(q.Data, matched.prefix))
+}
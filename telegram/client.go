@@ -2,89 +2,145 @@ package telegram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
+	"time"
 
 	"dnk.com/hoc-golang/middleware"
 )
 
-// TelegramClient quản lý token và gửi request tới Telegram API
+// TelegramClient quản lý token và gửi request tới Telegram API.
+// Mỗi method đều có bản ...Context nhận context.Context để đặt deadline/huỷ request;
+// bản không có ctx dùng context.Background().
 type TelegramClient struct {
-	Token  string
-	Client *http.Client
+	Token     string
+	Client    *http.Client
+	BaseURL   string // mặc định DefaultBaseURL
+	UserAgent string
+
+	timeout time.Duration
+	proxy   *url.URL
+	retry   RetryPolicy
+	limiter *outboundLimiter
+	fileIDs *FileIDCache
+
+	uploadLimits map[string]int64 // field → số byte tối đa, xem WithMaxUploadSize
 }
 
-// NewTelegramClient khởi tạo client với token
-func NewTelegramClient(token string) *TelegramClient {
-	return &TelegramClient{
-		Token:  token,
-		Client: &http.Client{},
+// NewTelegramClient khởi tạo client với token và các option tuỳ chọn
+func NewTelegramClient(token string, opts ...Option) *TelegramClient {
+	c := &TelegramClient{
+		Token:   token,
+		Client:  &http.Client{},
+		BaseURL: DefaultBaseURL,
+		retry:   DefaultRetryPolicy(),
+		limiter: newOutboundLimiter(DefaultRateLimits()),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.applyTransportOptions()
+	return c
 }
 
 // --------------------- Helpers chung ---------------------
 func (c *TelegramClient) buildURL(method string) string {
-	return fmt.Sprintf("https://api.telegram.org/bot%s/%s", c.Token, method)
+	return fmt.Sprintf("%s/bot%s/%s", c.BaseURL, c.Token, method)
+}
+
+// doOnce gửi request qua http.Client của struct, gắn User-Agent và đọc toàn bộ body
+func (c *TelegramClient) doOnce(method string, req *http.Request) ([]byte, int, error) {
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s request error: %w", method, c.redactURLError(err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("%s read body error: %v", method, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respBody, resp.StatusCode, parseAPIError(method, resp.StatusCode, respBody)
+	}
+	return respBody, resp.StatusCode, nil
+}
+
+// redactURLError ẩn token trong URL của *url.Error (message của nó chứa cả URL request),
+// vẫn giữ lỗi gốc bên trong cho errors.Is/As (vd context.Canceled khi retry)
+func (c *TelegramClient) redactURLError(err error) error {
+	var ue *url.Error
+	if !errors.As(err, &ue) {
+		return err
+	}
+	return &url.Error{Op: ue.Op, URL: redactToken(ue.URL, c.Token), Err: ue.Err}
 }
 
 // postJSON gửi payload JSON, trả về raw body + status code
-func (c *TelegramClient) postJSON(method string, payload interface{}) ([]byte, int, error) {
+func (c *TelegramClient) postJSON(ctx context.Context, method string, payload interface{}) ([]byte, int, error) {
 	if c.Token == "" {
 		return nil, 0, fmt.Errorf("⚠️ Telegram token empty")
 	}
-	url := c.buildURL(method)
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, 0, fmt.Errorf("marshal error: %v", err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
-	if err != nil {
-		return nil, 0, fmt.Errorf("%s request error: %v", method, err)
-	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return respBody, resp.StatusCode, fmt.Errorf("❌ %s failed, status: %s, response: %s", method, resp.Status, string(respBody))
+	var chatID int64
+	switch p := payload.(type) {
+	case map[string]interface{}:
+		chatID = chatIDFromValue(p["chat_id"])
+	case SendMessageRequest:
+		chatID = chatIDFromValue(p.ChatID)
 	}
-	return respBody, resp.StatusCode, nil
+	return c.do(ctx, method, chatID, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(method), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 }
 
-// postFormURLEncoded gửi application/x-www-form-urlencoded
-func (c *TelegramClient) PostFormURLEncoded(method string, data map[string]string) ([]byte, int, error) {
+// PostFormURLEncodedContext gửi application/x-www-form-urlencoded
+func (c *TelegramClient) PostFormURLEncodedContext(ctx context.Context, method string, data map[string]string) ([]byte, int, error) {
 	if c.Token == "" {
 		return nil, 0, fmt.Errorf("⚠️ Telegram token empty")
 	}
-	url := c.buildURL(method)
 
-	form := make([]string, 0, len(data))
+	form := url.Values{}
 	for k, v := range data {
-		form = append(form, fmt.Sprintf("%s=%s", k, v))
+		form.Set(k, v)
 	}
-	body := strings.Join(form, "&")
-	resp, err := http.Post(url, "application/x-www-form-urlencoded", strings.NewReader(body))
-	if err != nil {
-		return nil, 0, fmt.Errorf("%s request error: %v", method, err)
-	}
-	defer resp.Body.Close()
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return respBody, resp.StatusCode, fmt.Errorf("❌ %s failed, status: %s, response: %s", method, resp.Status, string(respBody))
-	}
-	return respBody, resp.StatusCode, nil
+	encoded := form.Encode()
+	return c.do(ctx, method, chatIDFromValue(data["chat_id"]), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(method), strings.NewReader(encoded))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+}
+
+func (c *TelegramClient) PostFormURLEncoded(method string, data map[string]string) ([]byte, int, error) {
+	return c.PostFormURLEncodedContext(context.Background(), method, data)
 }
 
 // postFile gửi multipart/form-data, skip nếu file không tồn tại
-func (c *TelegramClient) postFile(method string, chatID int64, fieldName, filePath string, extra map[string]string) ([]byte, int, error) {
+func (c *TelegramClient) postFile(ctx context.Context, method string, chatID int64, fieldName, filePath string, extra map[string]string) ([]byte, int, error) {
 	if c.Token == "" {
 		return nil, 0, fmt.Errorf("⚠️ Telegram token empty")
 	}
@@ -111,7 +167,7 @@ func (c *TelegramClient) postFile(method string, chatID int64, fieldName, filePa
 		for k, v := range extra {
 			payload[k] = v
 		}
-		return c.postJSON(method, payload)
+		return c.postJSON(ctx, method, payload)
 	}
 
 	// Skip nếu file không tồn tại
@@ -120,103 +176,208 @@ func (c *TelegramClient) postFile(method string, chatID int64, fieldName, filePa
 		return nil, 0, nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, 0, fmt.Errorf("open file error: %v", err)
+	if c.fileIDs == nil {
+		return c.uploadFile(ctx, method, chatID, fieldName, filePath, extra)
 	}
-	defer file.Close()
 
-	var b bytes.Buffer
-	writer := multipart.NewWriter(&b)
-	_ = writer.WriteField("chat_id", strconv.FormatInt(chatID, 10))
-	for k, v := range extra {
-		_ = writer.WriteField(k, v)
-	}
-
-	part, err := writer.CreateFormFile(fieldName, filepath.Base(filePath))
+	// File đã upload trước đó: gửi lại bằng file_id
+	key, err := fileCacheKey(filePath, fieldName)
 	if err != nil {
-		return nil, 0, fmt.Errorf("create form file error: %v", err)
+		return nil, 0, fmt.Errorf("hash file error: %v", err)
 	}
-	if _, err = io.Copy(part, file); err != nil {
-		return nil, 0, fmt.Errorf("copy file error: %v", err)
+	if fileID, ok := c.fileIDs.Get(key); ok {
+		payload := map[string]interface{}{"chat_id": chatID, fieldName: fileID}
+		for k, v := range extra {
+			payload[k] = v
+		}
+		data, status, err := c.postJSON(ctx, method, payload)
+		if err == nil || !isStaleFileID(err) {
+			return data, status, err
+		}
+		middleware.LogTelegramError("⚠️ Cached file_id rejected, re-uploading", err, map[string]interface{}{
+			"method": method, "file": filePath, "file_id": fileID,
+		})
+		_ = c.fileIDs.Delete(key)
+	}
+
+	data, status, err := c.uploadFile(ctx, method, chatID, fieldName, filePath, extra)
+	if err == nil {
+		if fileID := fileIDFromResponse(data, fieldName); fileID != "" {
+			if cacheErr := c.fileIDs.Set(key, fileID); cacheErr != nil {
+				middleware.LogTelegramError("⚠️ Cannot save file_id cache", cacheErr, map[string]interface{}{"file": filePath})
+			}
+		}
 	}
+	return data, status, err
+}
+
+// --------------------- SendMessage ---------------------
+
+// SentMessage là kết quả của một phần tin nhắn đã gửi
+type SentMessage struct {
+	MessageID int
+	Raw       []byte // body gốc Telegram trả về
+}
 
-	if err = writer.Close(); err != nil {
-		return nil, 0, fmt.Errorf("writer close error: %v", err)
+// sendMessageParts chia text theo SplitMessage và gửi lần lượt từng phần.
+// Chỉ phần đầu tiên giữ reply_parameters. Nếu một phần lỗi thì dừng lại,
+// trả về các phần đã gửi được cùng lỗi.
+func (c *TelegramClient) sendMessageParts(ctx context.Context, req SendMessageRequest) ([]SentMessage, int, error) {
+	req.normalize()
+	parts, entities := splitRequest(req)
+	sent := make([]SentMessage, 0, len(parts))
+	var status int
+	for i, part := range parts {
+		payload := req
+		payload.Text = part
+		payload.Entities = entities[i]
+		if i > 0 {
+			payload.ReplyParameters = nil
+		}
+		// Bàn phím chỉ gắn vào phần cuối cùng
+		if i < len(parts)-1 {
+			payload.ReplyMarkup = nil
+		}
+		data, st, err := c.postJSON(ctx, "sendMessage", payload)
+		status = st
+		if err != nil {
+			middleware.LogTelegramError("❌ SendMessageRaw failed", err, map[string]interface{}{
+				"chat_id": req.ChatID, "part": i + 1, "parts": len(parts), "text": part,
+			})
+			return sent, status, err
+		}
+		var resp SendMessageResponse
+		_ = resp.FromBytes(data)
+		sent = append(sent, SentMessage{MessageID: resp.Result.MessageID, Raw: data})
 	}
+	fmt.Printf("✅ Message sent to %v (%d part(s))\n", req.ChatID, len(sent))
+	return sent, status, nil
+}
 
-	req, err := http.NewRequest("POST", c.buildURL(method), &b)
-	if err != nil {
-		return nil, 0, err
+// SendMessageRawContext gửi text thuần; text dài hơn MaxMessageLength được chia thành nhiều
+// message có đánh số. Khi chỉ có 1 phần, body trả về y nguyên của Telegram;
+// khi nhiều phần, body có dạng {"ok":true,"message_ids":[...],"result":[...]}.
+func (c *TelegramClient) SendMessageRawContext(ctx context.Context, chatID int64, text string) ([]byte, int, error) {
+	return c.SendMessageRequestRawContext(ctx, SendMessageRequest{ChatID: chatID, Text: text})
+}
+
+// SendMessageRequestRawContext giống SendMessageRawContext nhưng nhận đủ tuỳ chọn
+// (parse_mode, entities, reply, link preview...)
+func (c *TelegramClient) SendMessageRequestRawContext(ctx context.Context, req SendMessageRequest) ([]byte, int, error) {
+	sent, status, err := c.sendMessageParts(ctx, req)
+	if err != nil && len(sent) == 0 {
+		return nil, status, err
+	}
+	if len(sent) == 1 && err == nil {
+		return sent[0].Raw, status, nil
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	// Lỗi giữa chừng: trả về các phần đã gửi cùng lỗi
+	return joinSentMessages(sent), status, err
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("%s request error: %v", method, err)
+func (c *TelegramClient) SendMessageRequestRaw(req SendMessageRequest) ([]byte, int, error) {
+	return c.SendMessageRequestRawContext(context.Background(), req)
+}
+
+// SendLongMessageContext gửi text (chia nhiều phần nếu cần) và trả về message_id của từng phần
+func (c *TelegramClient) SendLongMessageContext(ctx context.Context, chatID int64, text string) ([]int, error) {
+	sent, _, err := c.sendMessageParts(ctx, SendMessageRequest{ChatID: chatID, Text: text})
+	ids := make([]int, 0, len(sent))
+	for _, m := range sent {
+		ids = append(ids, m.MessageID)
 	}
-	defer resp.Body.Close()
+	return ids, err
+}
 
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return respBody, resp.StatusCode, fmt.Errorf("❌ %s failed, status: %s, response: %s", method, resp.Status, string(respBody))
+func (c *TelegramClient) SendLongMessage(chatID int64, text string) ([]int, error) {
+	return c.SendLongMessageContext(context.Background(), chatID, text)
+}
+
+// joinSentMessages gộp kết quả nhiều phần thành một JSON body
+func joinSentMessages(sent []SentMessage) []byte {
+	ids := make([]int, 0, len(sent))
+	results := make([]json.RawMessage, 0, len(sent))
+	for _, m := range sent {
+		ids = append(ids, m.MessageID)
+		var resp struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if json.Unmarshal(m.Raw, &resp) == nil {
+			results = append(results, resp.Result)
+		}
 	}
-	fmt.Printf("✅ %s sent: %s\n", method, filePath)
-	return respBody, resp.StatusCode, nil
+	body, _ := json.Marshal(map[string]interface{}{"ok": true, "message_ids": ids, "result": results})
+	return body
 }
 
-// --------------------- SendMessage ---------------------
 func (c *TelegramClient) SendMessageRaw(chatID int64, text string) ([]byte, int, error) {
-	const maxLen = 4000
-	if len(text) > maxLen {
-		text = text[:maxLen] + "\n... [truncated]"
-	}
-	payload := map[string]interface{}{"chat_id": chatID, "text": text}
-	data, status, err := c.postJSON("sendMessage", payload)
-	if err != nil {
-		middleware.LogTelegramError("❌ SendMessageRaw failed", err, map[string]interface{}{"chat_id": chatID, "text": text})
-		return data, status, err
-	}
-	fmt.Printf("✅ Message sent to %d\n", chatID)
-	return data, status, nil
+	return c.SendMessageRawContext(context.Background(), chatID, text)
 }
 
-func (c *TelegramClient) SendMessage(chatID int64, text string) error {
-	_, _, err := c.SendMessageRaw(chatID, text)
+func (c *TelegramClient) SendMessageContext(ctx context.Context, chatID int64, text string) error {
+	_, _, err := c.SendMessageRawContext(ctx, chatID, text)
 	return err
 }
 
+func (c *TelegramClient) SendMessage(chatID int64, text string) error {
+	return c.SendMessageContext(context.Background(), chatID, text)
+}
+
 // --------------------- Media Send Wrappers ---------------------
+func (c *TelegramClient) SendPhotoRawContext(ctx context.Context, chatID int64, filePath, caption string) ([]byte, int, error) {
+	return c.postFile(ctx, "sendPhoto", chatID, "photo", filePath, map[string]string{"caption": caption})
+}
 func (c *TelegramClient) SendPhotoRaw(chatID int64, filePath, caption string) ([]byte, int, error) {
-	return c.postFile("sendPhoto", chatID, "photo", filePath, map[string]string{"caption": caption})
+	return c.SendPhotoRawContext(context.Background(), chatID, filePath, caption)
+}
+
+func (c *TelegramClient) SendAudioRawContext(ctx context.Context, chatID int64, filePath, caption string) ([]byte, int, error) {
+	return c.postFile(ctx, "sendAudio", chatID, "audio", filePath, map[string]string{"caption": caption})
 }
 func (c *TelegramClient) SendAudioRaw(chatID int64, filePath, caption string) ([]byte, int, error) {
-	return c.postFile("sendAudio", chatID, "audio", filePath, map[string]string{"caption": caption})
+	return c.SendAudioRawContext(context.Background(), chatID, filePath, caption)
+}
+
+func (c *TelegramClient) SendDocumentRawContext(ctx context.Context, chatID int64, filePath, caption string) ([]byte, int, error) {
+	return c.postFile(ctx, "sendDocument", chatID, "document", filePath, map[string]string{"caption": caption})
 }
 func (c *TelegramClient) SendDocumentRaw(chatID int64, filePath, caption string) ([]byte, int, error) {
-	return c.postFile("sendDocument", chatID, "document", filePath, map[string]string{"caption": caption})
+	return c.SendDocumentRawContext(context.Background(), chatID, filePath, caption)
+}
+
+func (c *TelegramClient) SendVideoRawContext(ctx context.Context, chatID int64, filePath, caption string) ([]byte, int, error) {
+	return c.postFile(ctx, "sendVideo", chatID, "video", filePath, map[string]string{"caption": caption})
 }
 func (c *TelegramClient) SendVideoRaw(chatID int64, filePath, caption string) ([]byte, int, error) {
-	return c.postFile("sendVideo", chatID, "video", filePath, map[string]string{"caption": caption})
+	return c.SendVideoRawContext(context.Background(), chatID, filePath, caption)
+}
+
+func (c *TelegramClient) SendAnimationRawContext(ctx context.Context, chatID int64, filePath, caption string) ([]byte, int, error) {
+	return c.postFile(ctx, "sendAnimation", chatID, "animation", filePath, map[string]string{"caption": caption})
 }
 func (c *TelegramClient) SendAnimationRaw(chatID int64, filePath, caption string) ([]byte, int, error) {
-	return c.postFile("sendAnimation", chatID, "animation", filePath, map[string]string{"caption": caption})
+	return c.SendAnimationRawContext(context.Background(), chatID, filePath, caption)
+}
+
+func (c *TelegramClient) SendVoiceRawContext(ctx context.Context, chatID int64, filePath, caption string) ([]byte, int, error) {
+	return c.postFile(ctx, "sendVoice", chatID, "voice", filePath, map[string]string{"caption": caption})
 }
 func (c *TelegramClient) SendVoiceRaw(chatID int64, filePath, caption string) ([]byte, int, error) {
-	return c.postFile("sendVoice", chatID, "voice", filePath, map[string]string{"caption": caption})
+	return c.SendVoiceRawContext(context.Background(), chatID, filePath, caption)
 }
 
 // --------------------- GetUpdates ---------------------
 
 // GetUpdatesParams chứa các tham số cho GetUpdates
 type GetUpdatesParams struct {
-	Offset  int // ID update lớn nhất đã xử lý + 1
-	Limit   int // Số updates tối đa (1-100)
-	Timeout int // Thời gian chờ (giây)
+	Offset         int      // ID update lớn nhất đã xử lý + 1
+	Limit          int      // Số updates tối đa (1-100)
+	Timeout        int      // Thời gian chờ (giây)
+	AllowedUpdates []string // Loại update muốn nhận, nil = giữ cấu hình trước đó
 }
 
-// GetUpdatesV2 phiên bản cải tiến với xử lý offset tự động
-func (c *TelegramClient) GetUpdatesV2(params GetUpdatesParams) ([]Update, error) {
+// GetUpdatesV2Context phiên bản cải tiến với xử lý offset tự động
+func (c *TelegramClient) GetUpdatesV2Context(ctx context.Context, params GetUpdatesParams) ([]Update, error) {
 	// Validate các tham số
 	if params.Limit < 0 || params.Limit > 100 {
 		return nil, fmt.Errorf("limit must be between 1-100")
@@ -234,10 +395,13 @@ func (c *TelegramClient) GetUpdatesV2(params GetUpdatesParams) ([]Update, error)
 	if params.Timeout > 0 {
 		payload["timeout"] = params.Timeout
 	}
+	if params.AllowedUpdates != nil {
+		payload["allowed_updates"] = params.AllowedUpdates
+	}
 
-	respBody, _, err := c.postJSON("getUpdates", payload)
+	respBody, _, err := c.postJSON(ctx, "getUpdates", payload)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 
 	var result struct {
@@ -254,34 +418,32 @@ func (c *TelegramClient) GetUpdatesV2(params GetUpdatesParams) ([]Update, error)
 	return result.Result, nil
 }
 
-// FetchUpdatesRaw 
+func (c *TelegramClient) GetUpdatesV2(params GetUpdatesParams) ([]Update, error) {
+	return c.GetUpdatesV2Context(context.Background(), params)
+}
+
+// FetchUpdatesRawContext gọi getUpdates bằng GET với query string có sẵn (vd "?offset=0&limit=100")
+func (c *TelegramClient) FetchUpdatesRawContext(ctx context.Context, params string) ([]byte, int, error) {
+	if c.Token == "" {
+		return nil, 0, fmt.Errorf("⚠️ Telegram token empty")
+	}
+	return c.do(ctx, "getUpdates", 0, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, c.buildURL("getUpdates")+params, nil)
+	})
+}
+
 func (c *TelegramClient) FetchUpdatesRaw(params string) ([]byte, int, error) {
-    if c.Token == "" {
-        return nil, 0, fmt.Errorf("⚠️ Telegram token empty")
-    }
-    url := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates%s", c.Token, params)
-    resp, err := http.Get(url)
-    if err != nil {
-        return nil, 0, err
-    }
-    defer resp.Body.Close()
-    resBody, err := io.ReadAll(resp.Body)
-    if err != nil {
-        return nil, resp.StatusCode, err
-    }
-    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-        return resBody, resp.StatusCode, fmt.Errorf("❌ getUpdates error: status %s, body: %s", resp.Status, string(resBody))
-    }
-    return resBody, resp.StatusCode, nil
-}
-// GetUpdatesWithOffset (giữ nguyên để tương thích)
-func (c *TelegramClient) GetUpdatesWithOffset(offset, limit int) ([]Update, error) {
+	return c.FetchUpdatesRawContext(context.Background(), params)
+}
+
+// GetUpdatesWithOffsetContext (giữ nguyên để tương thích)
+func (c *TelegramClient) GetUpdatesWithOffsetContext(ctx context.Context, offset, limit int) ([]Update, error) {
 	payload := map[string]interface{}{
 		"offset": offset,
 		"limit":  limit,
 	}
 
-	respBody, _, err := c.postJSON("getUpdates", payload)
+	respBody, _, err := c.postJSON(ctx, "getUpdates", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -299,16 +461,94 @@ func (c *TelegramClient) GetUpdatesWithOffset(offset, limit int) ([]Update, erro
 	return result.Result, nil
 }
 
+func (c *TelegramClient) GetUpdatesWithOffset(offset, limit int) ([]Update, error) {
+	return c.GetUpdatesWithOffsetContext(context.Background(), offset, limit)
+}
+
+// --------------------- GetMe ---------------------
+
+// GetMeContext lấy thông tin của chính bot (username dùng để nhận lệnh dạng /cmd@bot)
+func (c *TelegramClient) GetMeContext(ctx context.Context) (*User, error) {
+	respBody, _, err := c.postJSON(ctx, "getMe", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Ok     bool `json:"ok"`
+		Result User `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	return &result.Result, nil
+}
+
+func (c *TelegramClient) GetMe() (*User, error) {
+	return c.GetMeContext(context.Background())
+}
+
+// --------------------- Bot commands ---------------------
+
+// BotCommandScope giới hạn danh sách lệnh theo nơi hiển thị; nil = default (mọi chat)
+type BotCommandScope struct {
+	Type   string `json:"type"` // default, all_private_chats, all_group_chats, all_chat_administrators, chat, chat_administrators, chat_member
+	ChatID int64  `json:"chat_id,omitempty"`
+	UserID int64  `json:"user_id,omitempty"`
+}
+
+// SetMyCommandsContext đăng ký danh sách lệnh hiện trong menu "/" của client Telegram
+func (c *TelegramClient) SetMyCommandsContext(ctx context.Context, commands []CommandInfo, scope *BotCommandScope) ([]byte, int, error) {
+	payload := map[string]interface{}{"commands": commands}
+	if scope != nil {
+		payload["scope"] = scope
+	}
+	return c.postJSON(ctx, "setMyCommands", payload)
+}
+
+func (c *TelegramClient) SetMyCommands(commands []CommandInfo, scope *BotCommandScope) ([]byte, int, error) {
+	return c.SetMyCommandsContext(context.Background(), commands, scope)
+}
+
+// GetChatMemberContext lấy trạng thái của user trong chat (dùng để kiểm tra quyền admin)
+func (c *TelegramClient) GetChatMemberContext(ctx context.Context, chatID, userID int64) (*ChatMember, error) {
+	respBody, _, err := c.postJSON(ctx, "getChatMember", map[string]interface{}{"chat_id": chatID, "user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Ok     bool       `json:"ok"`
+		Result ChatMember `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	return &result.Result, nil
+}
+
+func (c *TelegramClient) GetChatMember(chatID, userID int64) (*ChatMember, error) {
+	return c.GetChatMemberContext(context.Background(), chatID, userID)
+}
+
 // --------------------- Member management ---------------------
-func (c *TelegramClient) BanChatMemberRaw(chatID, userID, untilDate int64) ([]byte, int, error) {
+func (c *TelegramClient) BanChatMemberRawContext(ctx context.Context, chatID, userID, untilDate int64) ([]byte, int, error) {
 	payload := map[string]interface{}{"chat_id": chatID, "user_id": userID, "until_date": untilDate}
-	return c.postJSON("banChatMember", payload)
+	return c.postJSON(ctx, "banChatMember", payload)
 }
-func (c *TelegramClient) UnbanChatMemberRaw(chatID, userID int64, onlyIfBanned bool) ([]byte, int, error) {
+
+func (c *TelegramClient) BanChatMemberRaw(chatID, userID, untilDate int64) ([]byte, int, error) {
+	return c.BanChatMemberRawContext(context.Background(), chatID, userID, untilDate)
+}
+func (c *TelegramClient) UnbanChatMemberRawContext(ctx context.Context, chatID, userID int64, onlyIfBanned bool) ([]byte, int, error) {
 	payload := map[string]interface{}{"chat_id": chatID, "user_id": userID, "only_if_banned": onlyIfBanned}
-	return c.postJSON("unbanChatMember", payload)
+	return c.postJSON(ctx, "unbanChatMember", payload)
 }
-func (c *TelegramClient) CreateChatInviteLinkRaw(chatID int64, name string, expireDate int64, memberLimit int) ([]byte, int, error) {
+
+func (c *TelegramClient) UnbanChatMemberRaw(chatID, userID int64, onlyIfBanned bool) ([]byte, int, error) {
+	return c.UnbanChatMemberRawContext(context.Background(), chatID, userID, onlyIfBanned)
+}
+func (c *TelegramClient) CreateChatInviteLinkRawContext(ctx context.Context, chatID int64, name string, expireDate int64, memberLimit int) ([]byte, int, error) {
 	payload := map[string]interface{}{"chat_id": chatID}
 	if name != "" {
 		payload["name"] = name
@@ -319,44 +559,112 @@ func (c *TelegramClient) CreateChatInviteLinkRaw(chatID int64, name string, expi
 	if memberLimit > 0 {
 		payload["member_limit"] = memberLimit
 	}
-	return c.postJSON("createChatInviteLink", payload)
+	return c.postJSON(ctx, "createChatInviteLink", payload)
+}
+
+func (c *TelegramClient) CreateChatInviteLinkRaw(chatID int64, name string, expireDate int64, memberLimit int) ([]byte, int, error) {
+	return c.CreateChatInviteLinkRawContext(context.Background(), chatID, name, expireDate, memberLimit)
 }
  // --------------------- Pin / Unpin / Edit / Delete Messages ---------------------
 
-// PinChatMessageRaw pin một message trong chat
-func (c *TelegramClient) PinChatMessageRaw(chatID int64, messageID int) ([]byte, int, error) {
+// PinChatMessageRawContext pin một message trong chat
+func (c *TelegramClient) PinChatMessageRawContext(ctx context.Context, chatID int64, messageID int) ([]byte, int, error) {
 	payload := map[string]interface{}{
 		"chat_id":              chatID,
 		"message_id":           messageID,
 		"disable_notification": false, // default false
 	}
-	return c.postJSON("pinChatMessage", payload)
+	return c.postJSON(ctx, "pinChatMessage", payload)
+}
+
+func (c *TelegramClient) PinChatMessageRaw(chatID int64, messageID int) ([]byte, int, error) {
+	return c.PinChatMessageRawContext(context.Background(), chatID, messageID)
+}
+
+// UnpinChatMessageRawContext unpin một message trong chat; messageID = 0 thì unpin message được ghim gần nhất
+func (c *TelegramClient) UnpinChatMessageRawContext(ctx context.Context, chatID int64, messageID int) ([]byte, int, error) {
+	payload := map[string]interface{}{
+		"chat_id": chatID,
+	}
+	if messageID != 0 {
+		payload["message_id"] = messageID
+	}
+	return c.postJSON(ctx, "unpinChatMessage", payload)
 }
 
-// UnpinChatMessageRaw unpin một message trong chat
 func (c *TelegramClient) UnpinChatMessageRaw(chatID int64, messageID int) ([]byte, int, error) {
+	return c.UnpinChatMessageRawContext(context.Background(), chatID, messageID)
+}
+
+// EditMessageTextRawContext chỉnh sửa text của message đã gửi
+func (c *TelegramClient) EditMessageTextRawContext(ctx context.Context, chatID int64, messageID int, newText string) ([]byte, int, error) {
+	return c.EditMessageTextMarkupRawContext(ctx, chatID, messageID, newText, nil)
+}
+
+func (c *TelegramClient) EditMessageTextRaw(chatID int64, messageID int, newText string) ([]byte, int, error) {
+	return c.EditMessageTextRawContext(context.Background(), chatID, messageID, newText)
+}
+
+// EditMessageTextMarkupRawContext chỉnh sửa text và thay luôn inline keyboard (markup nil = giữ nguyên)
+func (c *TelegramClient) EditMessageTextMarkupRawContext(ctx context.Context, chatID int64, messageID int, newText string, markup interface{}) ([]byte, int, error) {
 	payload := map[string]interface{}{
 		"chat_id":    chatID,
 		"message_id": messageID,
+		"text":       newText,
+	}
+	if markup != nil {
+		payload["reply_markup"] = markup
 	}
-	return c.postJSON("unpinChatMessage", payload)
+	return c.postJSON(ctx, "editMessageText", payload)
 }
 
-// EditMessageTextRaw chỉnh sửa text của message đã gửi
-func (c *TelegramClient) EditMessageTextRaw(chatID int64, messageID int, newText string) ([]byte, int, error) {
+// EditMessageReplyMarkupRawContext chỉ thay inline keyboard của message; markup nil = xoá keyboard
+func (c *TelegramClient) EditMessageReplyMarkupRawContext(ctx context.Context, chatID int64, messageID int, markup *InlineKeyboardMarkup) ([]byte, int, error) {
 	payload := map[string]interface{}{
 		"chat_id":    chatID,
 		"message_id": messageID,
-		"text":       newText,
 	}
-	return c.postJSON("editMessageText", payload)
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	return c.postJSON(ctx, "editMessageReplyMarkup", payload)
 }
 
-// DeleteMessageRaw xóa message đã gửi
-func (c *TelegramClient) DeleteMessageRaw(chatID int64, messageID int) ([]byte, int, error) {
+func (c *TelegramClient) EditMessageReplyMarkupRaw(chatID int64, messageID int, markup *InlineKeyboardMarkup) ([]byte, int, error) {
+	return c.EditMessageReplyMarkupRawContext(context.Background(), chatID, messageID, markup)
+}
+
+// AnswerCallbackQueryRawContext trả lời callback query, có thể hiện toast/alert cho người bấm
+func (c *TelegramClient) AnswerCallbackQueryRawContext(ctx context.Context, req AnswerCallbackQueryRequest) ([]byte, int, error) {
+	return c.postJSON(ctx, "answerCallbackQuery", req)
+}
+
+func (c *TelegramClient) AnswerCallbackQueryRaw(req AnswerCallbackQueryRequest) ([]byte, int, error) {
+	return c.AnswerCallbackQueryRawContext(context.Background(), req)
+}
+
+// DeleteMessageRawContext xóa message đã gửi
+func (c *TelegramClient) DeleteMessageRawContext(ctx context.Context, chatID int64, messageID int) ([]byte, int, error) {
 	payload := map[string]interface{}{
 		"chat_id":    chatID,
 		"message_id": messageID,
 	}
-	return c.postJSON("deleteMessage", payload)
+	return c.postJSON(ctx, "deleteMessage", payload)
+}
+
+func (c *TelegramClient) DeleteMessageRaw(chatID int64, messageID int) ([]byte, int, error) {
+	return c.DeleteMessageRawContext(context.Background(), chatID, messageID)
+}
+
+// MaxDeleteMessages là số message tối đa mỗi lần gọi deleteMessages
+const MaxDeleteMessages = 100
+
+// DeleteMessagesRawContext xoá nhiều message (tối đa MaxDeleteMessages) trong một lần gọi;
+// message không còn tồn tại được Telegram bỏ qua
+func (c *TelegramClient) DeleteMessagesRawContext(ctx context.Context, chatID int64, messageIDs []int) ([]byte, int, error) {
+	payload := map[string]interface{}{
+		"chat_id":     chatID,
+		"message_ids": messageIDs,
+	}
+	return c.postJSON(ctx, "deleteMessages", payload)
 }
@@ -0,0 +1,482 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dnk.com/hoc-golang/middleware"
+)
+
+// ConversationKey xác định một hội thoại: cùng user ở hai chat khác nhau là hai hội thoại riêng
+type ConversationKey struct {
+	ChatID int64 `json:"chat_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (k ConversationKey) String() string {
+	return fmt.Sprintf("%d:%d", k.ChatID, k.UserID)
+}
+
+func parseConversationKey(s string) (ConversationKey, error) {
+	chat, user, ok := strings.Cut(s, ":")
+	if !ok {
+		return ConversationKey{}, fmt.Errorf("invalid conversation key %q", s)
+	}
+	chatID, err := strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return ConversationKey{}, err
+	}
+	userID, err := strconv.ParseInt(user, 10, 64)
+	if err != nil {
+		return ConversationKey{}, err
+	}
+	return ConversationKey{ChatID: chatID, UserID: userID}, nil
+}
+
+// ConversationKeyFor lấy key từ update; ok=false nếu update không có chat hoặc người gửi
+func ConversationKeyFor(u *Update) (ConversationKey, bool) {
+	chat, sender := u.Chat(), u.Sender()
+	if chat == nil || sender == nil {
+		return ConversationKey{}, false
+	}
+	return ConversationKey{ChatID: chat.ID, UserID: int64(sender.ID)}, true
+}
+
+// ConversationState là trạng thái đang lưu của một hội thoại
+type ConversationState struct {
+	Flow      string            `json:"flow"`
+	Step      string            `json:"step"`
+	Data      map[string]string `json:"data,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// ConversationStore lưu trạng thái hội thoại; Get trả nil nếu không có
+type ConversationStore interface {
+	Get(key ConversationKey) (*ConversationState, error)
+	Set(key ConversationKey, state *ConversationState) error
+	Delete(key ConversationKey) error
+	All() (map[ConversationKey]*ConversationState, error)
+}
+
+// ---- Store trong RAM ----
+
+// MemoryConversationStore mất hết hội thoại khi restart, hợp cho dev/test
+type MemoryConversationStore struct {
+	mu     sync.Mutex
+	states map[ConversationKey]ConversationState
+}
+
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{states: make(map[ConversationKey]ConversationState)}
+}
+
+func (m *MemoryConversationStore) Get(key ConversationKey) (*ConversationState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[key]
+	if !ok {
+		return nil, nil
+	}
+	return cloneState(&state), nil
+}
+
+func (m *MemoryConversationStore) Set(key ConversationKey, state *ConversationState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[key] = *cloneState(state)
+	return nil
+}
+
+func (m *MemoryConversationStore) Delete(key ConversationKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, key)
+	return nil
+}
+
+func (m *MemoryConversationStore) All() (map[ConversationKey]*ConversationState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := make(map[ConversationKey]*ConversationState, len(m.states))
+	for k, v := range m.states {
+		all[k] = cloneState(&v)
+	}
+	return all, nil
+}
+
+// ---- Store ghi ra file ----
+
+// FileConversationStore giữ hội thoại trong RAM và ghi ra file JSON sau mỗi thay đổi
+type FileConversationStore struct {
+	path string
+	mem  *MemoryConversationStore
+	mu   sync.Mutex // tuần tự hoá việc ghi file
+}
+
+// NewFileConversationStore mở (hoặc tạo mới) store tại path
+func NewFileConversationStore(path string) (*FileConversationStore, error) {
+	f := &FileConversationStore{path: path, mem: NewMemoryConversationStore()}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read conversation store: %v", err)
+	}
+	var raw map[string]ConversationState
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("decode conversation store: %v", err)
+		}
+	}
+	for k, v := range raw {
+		key, err := parseConversationKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("decode conversation store: %v", err)
+		}
+		f.mem.states[key] = v
+	}
+	return f, nil
+}
+
+func (f *FileConversationStore) Get(key ConversationKey) (*ConversationState, error) {
+	return f.mem.Get(key)
+}
+
+func (f *FileConversationStore) Set(key ConversationKey, state *ConversationState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.mem.Set(key, state)
+	return f.saveLocked()
+}
+
+func (f *FileConversationStore) Delete(key ConversationKey) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.mem.Delete(key)
+	return f.saveLocked()
+}
+
+func (f *FileConversationStore) All() (map[ConversationKey]*ConversationState, error) {
+	return f.mem.All()
+}
+
+// saveLocked ghi ra file tạm rồi rename như FileIDCache
+func (f *FileConversationStore) saveLocked() error {
+	all, _ := f.mem.All()
+	raw := make(map[string]*ConversationState, len(all))
+	for k, v := range all {
+		raw[k.String()] = v
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func cloneState(s *ConversationState) *ConversationState {
+	c := *s
+	if s.Data != nil {
+		c.Data = make(map[string]string, len(s.Data))
+		for k, v := range s.Data {
+			c.Data[k] = v
+		}
+	}
+	return &c
+}
+
+// ---- Flow và Conversation ----
+
+// StepHandlerFunc xử lý update gửi tới khi hội thoại đang ở một bước.
+// Gọi conv.Next để sang bước khác, conv.End để kết thúc; không gọi gì thì ở lại bước hiện tại.
+// Trả lỗi thì trạng thái được giữ nguyên như trước khi gọi.
+type StepHandlerFunc func(ctx context.Context, conv *Conversation, u *Update) error
+
+// Flow là một hội thoại nhiều bước, vd "ban": user → duration → confirm
+type Flow struct {
+	Name    string
+	Start   string                     // bước đầu tiên sau Begin
+	Steps   map[string]StepHandlerFunc // tên bước → handler
+	Timeout time.Duration              // 0 = dùng mặc định của ConversationManager
+}
+
+// Conversation là hội thoại đang xử lý, truyền cho StepHandlerFunc
+type Conversation struct {
+	Key   ConversationKey
+	State *ConversationState
+	ended bool
+}
+
+// Next chuyển sang bước step khi handler trả về
+func (c *Conversation) Next(step string) {
+	c.State.Step = step
+}
+
+// End kết thúc hội thoại, trạng thái bị xoá khỏi store
+func (c *Conversation) End() {
+	c.ended = true
+}
+
+func (c *Conversation) Get(key string) string {
+	return c.State.Data[key]
+}
+
+func (c *Conversation) Set(key, value string) {
+	if c.State.Data == nil {
+		c.State.Data = map[string]string{}
+	}
+	c.State.Data[key] = value
+}
+
+// Các lý do hội thoại kết thúc ngoài ý muốn, truyền cho ConversationManager.OnAbort
+const (
+	ConversationCanceled = "canceled"
+	ConversationTimedOut = "timeout"
+)
+
+// ConversationManager giữ các Flow và định tuyến update của user đang trong hội thoại
+type ConversationManager struct {
+	store   ConversationStore
+	timeout time.Duration
+
+	// CancelCommands là các lệnh huỷ hội thoại đang dở, mặc định "cancel"
+	CancelCommands []string
+	// OnAbort được gọi khi hội thoại bị huỷ hoặc hết hạn (vd để báo cho user), có thể nil
+	OnAbort func(ctx context.Context, key ConversationKey, state *ConversationState, reason string)
+
+	mu    sync.RWMutex
+	flows map[string]*Flow
+}
+
+// NewConversationManager tạo manager; timeout là thời gian chờ mặc định giữa hai bước
+func NewConversationManager(store ConversationStore, timeout time.Duration) *ConversationManager {
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	return &ConversationManager{
+		store:          store,
+		timeout:        timeout,
+		CancelCommands: []string{"cancel"},
+		flows:          make(map[string]*Flow),
+	}
+}
+
+// Register thêm flow; flow cùng tên được thay thế
+func (m *ConversationManager) Register(flow *Flow) {
+	if _, ok := flow.Steps[flow.Start]; !ok {
+		panic(fmt.Sprintf("telegram: flow %q has no start step %q", flow.Name, flow.Start))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flows[flow.Name] = flow
+}
+
+func (m *ConversationManager) flow(name string) *Flow {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.flows[name]
+}
+
+func (m *ConversationManager) timeoutFor(flow *Flow) time.Duration {
+	if flow.Timeout > 0 {
+		return flow.Timeout
+	}
+	return m.timeout
+}
+
+// Begin bắt đầu flow cho key (thay thế hội thoại đang dở nếu có); update tiếp theo của user
+// sẽ vào bước flow.Start. data là dữ liệu ban đầu, có thể nil.
+func (m *ConversationManager) Begin(key ConversationKey, flowName string, data map[string]string) error {
+	flow := m.flow(flowName)
+	if flow == nil {
+		return fmt.Errorf("unknown conversation flow %q", flowName)
+	}
+	now := time.Now()
+	return m.store.Set(key, &ConversationState{
+		Flow:      flowName,
+		Step:      flow.Start,
+		Data:      data,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(m.timeoutFor(flow)),
+	})
+}
+
+// Active trả về trạng thái hội thoại còn hạn của key, nil nếu không có
+func (m *ConversationManager) Active(key ConversationKey) (*ConversationState, error) {
+	state, err := m.store.Get(key)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	if time.Now().After(state.ExpiresAt) {
+		return nil, nil
+	}
+	return state, nil
+}
+
+// Cancel huỷ hội thoại của key; trả về false nếu không có hội thoại nào
+func (m *ConversationManager) Cancel(ctx context.Context, key ConversationKey) (bool, error) {
+	state, err := m.Active(key)
+	if err != nil || state == nil {
+		return false, err
+	}
+	if err := m.store.Delete(key); err != nil {
+		return false, err
+	}
+	m.abort(ctx, key, state, ConversationCanceled)
+	return true, nil
+}
+
+// Sweep xoá các hội thoại đã hết hạn và gọi OnAbort; trả về số hội thoại bị xoá
+func (m *ConversationManager) Sweep(ctx context.Context) int {
+	all, err := m.store.All()
+	if err != nil {
+		middleware.LogTelegramError("⚠️ Conversation sweep failed", err, nil)
+		return 0
+	}
+	now := time.Now()
+	removed := 0
+	for key, state := range all {
+		if now.Before(state.ExpiresAt) {
+			continue
+		}
+		if err := m.store.Delete(key); err != nil {
+			middleware.LogTelegramError("⚠️ Cannot delete expired conversation", err, map[string]interface{}{"key": key.String()})
+			continue
+		}
+		removed++
+		m.abort(ctx, key, state, ConversationTimedOut)
+	}
+	return removed
+}
+
+// RunSweeper gọi Sweep mỗi interval tới khi ctx bị huỷ, để user được báo hết hạn mà không cần gửi gì
+func (m *ConversationManager) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sweep(ctx)
+		}
+	}
+}
+
+func (m *ConversationManager) abort(ctx context.Context, key ConversationKey, state *ConversationState, reason string) {
+	middleware.LogTelegramInfo("💬 Conversation aborted", map[string]interface{}{
+		"key": key.String(), "flow": state.Flow, "step": state.Step, "reason": reason,
+	})
+	if m.OnAbort != nil {
+		m.OnAbort(ctx, key, state, reason)
+	}
+}
+
+func (m *ConversationManager) isCancel(u *Update) bool {
+	if u.Message == nil {
+		return false
+	}
+	cmd, ok := ParseCommand(u.Message.Text)
+	if !ok {
+		return false
+	}
+	for _, c := range m.CancelCommands {
+		if cmd.Name == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware chặn update của user đang trong hội thoại và chuyển cho bước hiện tại;
+// update của user không có hội thoại đi tiếp như bình thường. Dùng với Dispatcher.Use.
+func (m *ConversationManager) Middleware() Middleware {
+	return func(next UpdateHandlerFunc) UpdateHandlerFunc {
+		return func(ctx context.Context, u *Update) error {
+			key, ok := ConversationKeyFor(u)
+			if !ok {
+				return next(ctx, u)
+			}
+			state, err := m.store.Get(key)
+			if err != nil {
+				return err
+			}
+			if state == nil {
+				return next(ctx, u)
+			}
+
+			if time.Now().After(state.ExpiresAt) {
+				if err := m.store.Delete(key); err != nil {
+					return err
+				}
+				m.abort(ctx, key, state, ConversationTimedOut)
+				return next(ctx, u)
+			}
+			if m.isCancel(u) {
+				if err := m.store.Delete(key); err != nil {
+					return err
+				}
+				m.abort(ctx, key, state, ConversationCanceled)
+				return nil
+			}
+
+			flow := m.flow(state.Flow)
+			step, ok := flow.stepOrNil(state.Step)
+			if !ok {
+				// Flow/bước đã bị bỏ khi deploy bản mới: xoá để user không bị kẹt
+				middleware.LogTelegramError("⚠️ Unknown conversation step, dropping", nil, map[string]interface{}{
+					"key": key.String(), "flow": state.Flow, "step": state.Step,
+				})
+				if err := m.store.Delete(key); err != nil {
+					return err
+				}
+				return next(ctx, u)
+			}
+			return m.runStep(ctx, flow, step, key, state, u)
+		}
+	}
+}
+
+func (f *Flow) stepOrNil(name string) (StepHandlerFunc, bool) {
+	if f == nil {
+		return nil, false
+	}
+	step, ok := f.Steps[name]
+	return step, ok
+}
+
+func (m *ConversationManager) runStep(ctx context.Context, flow *Flow, step StepHandlerFunc, key ConversationKey, state *ConversationState, u *Update) error {
+	conv := &Conversation{Key: key, State: cloneState(state)}
+	if err := step(ctx, conv, u); err != nil {
+		return fmt.Errorf("conversation %s/%s: %w", state.Flow, state.Step, err)
+	}
+	if conv.ended {
+		return m.store.Delete(key)
+	}
+	if _, ok := flow.Steps[conv.State.Step]; !ok {
+		return fmt.Errorf("conversation %s: unknown next step %q", state.Flow, conv.State.Step)
+	}
+	now := time.Now()
+	conv.State.UpdatedAt = now
+	conv.State.ExpiresAt = now.Add(m.timeoutFor(flow))
+	return m.store.Set(key, conv.State)
+}
@@ -0,0 +1,180 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func textUpdate(chatID int64, userID int, text string) *Update {
+	return &Update{Message: &Message{Chat: Chat{ID: chatID}, From: &User{ID: userID}, Text: text}}
+}
+
+// testFlow: "name" → "age" → kết thúc; "fail" trả lỗi ở bước age
+func testFlow() *Flow {
+	return &Flow{
+		Name:  "signup",
+		Start: "name",
+		Steps: map[string]StepHandlerFunc{
+			"name": func(ctx context.Context, conv *Conversation, u *Update) error {
+				conv.Set("name", u.Message.Text)
+				conv.Next("age")
+				return nil
+			},
+			"age": func(ctx context.Context, conv *Conversation, u *Update) error {
+				switch u.Message.Text {
+				case "fail":
+					conv.Set("age", "broken")
+					return errors.New("boom")
+				case "bogus":
+					conv.Next("missing")
+					return nil
+				}
+				conv.Set("age", u.Message.Text)
+				conv.End()
+				return nil
+			},
+		},
+	}
+}
+
+func TestConversationManagerFlow(t *testing.T) {
+	m := NewConversationManager(NewMemoryConversationStore(), time.Minute)
+	m.Register(testFlow())
+	var aborted []string
+	m.OnAbort = func(ctx context.Context, key ConversationKey, state *ConversationState, reason string) {
+		aborted = append(aborted, reason)
+	}
+	passed := 0
+	handler := m.Middleware()(func(ctx context.Context, u *Update) error {
+		passed++
+		return nil
+	})
+	ctx := context.Background()
+	key := ConversationKey{ChatID: -100, UserID: 7}
+
+	steps := []struct {
+		update    *Update
+		wantErr   bool
+		wantStep  string // "" = không còn hội thoại
+		wantName  string
+		wantPass  int
+		wantAbort int
+	}{
+		{update: textUpdate(-100, 7, "trước Begin"), wantPass: 1},
+		{update: nil}, // Begin
+		{update: textUpdate(-100, 8, "user khác"), wantStep: "name", wantPass: 2},
+		{update: textUpdate(-100, 7, "Lan"), wantStep: "age", wantName: "Lan", wantPass: 2},
+		{update: textUpdate(-100, 7, "fail"), wantErr: true, wantStep: "age", wantName: "Lan", wantPass: 2},
+		{update: textUpdate(-100, 7, "bogus"), wantErr: true, wantStep: "age", wantName: "Lan", wantPass: 2},
+		{update: textUpdate(-100, 7, "20"), wantPass: 2},
+		{update: nil}, // Begin lại
+		{update: textUpdate(-100, 7, "/cancel"), wantPass: 2, wantAbort: 1},
+		{update: textUpdate(-100, 7, "/cancel"), wantPass: 3, wantAbort: 1},
+	}
+	for i, st := range steps {
+		if st.update == nil {
+			if err := m.Begin(key, "signup", nil); err != nil {
+				t.Fatalf("step %d: Begin error %v", i, err)
+			}
+			continue
+		}
+		err := handler(ctx, st.update)
+		if (err != nil) != st.wantErr {
+			t.Fatalf("step %d: err = %v, wantErr %v", i, err, st.wantErr)
+		}
+		state, _ := m.Active(key)
+		gotStep, gotName := "", ""
+		if state != nil {
+			gotStep, gotName = state.Step, state.Data["name"]
+			if state.Data["age"] == "broken" {
+				t.Fatalf("step %d: failed step leaked data into store", i)
+			}
+		}
+		if gotStep != st.wantStep || gotName != st.wantName {
+			t.Fatalf("step %d: state = (%q, %q), want (%q, %q)", i, gotStep, gotName, st.wantStep, st.wantName)
+		}
+		if passed != st.wantPass || len(aborted) != st.wantAbort {
+			t.Fatalf("step %d: passed=%d aborted=%d, want %d/%d", i, passed, len(aborted), st.wantPass, st.wantAbort)
+		}
+	}
+}
+
+func TestConversationManagerBeginUnknownFlow(t *testing.T) {
+	m := NewConversationManager(NewMemoryConversationStore(), time.Minute)
+	if err := m.Begin(ConversationKey{ChatID: 1, UserID: 1}, "nope", nil); err == nil {
+		t.Fatal("Begin with unknown flow should fail")
+	}
+}
+
+func TestConversationManagerExpiry(t *testing.T) {
+	store := NewMemoryConversationStore()
+	m := NewConversationManager(store, time.Minute)
+	m.Register(testFlow())
+	var reasons []string
+	m.OnAbort = func(ctx context.Context, key ConversationKey, state *ConversationState, reason string) {
+		reasons = append(reasons, reason)
+	}
+	ctx := context.Background()
+	expired := ConversationKey{ChatID: 1, UserID: 1}
+	live := ConversationKey{ChatID: 1, UserID: 2}
+	for _, k := range []ConversationKey{expired, live} {
+		if err := m.Begin(k, "signup", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, _ := store.Get(expired)
+	state.ExpiresAt = time.Now().Add(-time.Second)
+	_ = store.Set(expired, state)
+
+	if s, _ := m.Active(expired); s != nil {
+		t.Fatal("Active returned an expired conversation")
+	}
+	if n := m.Sweep(ctx); n != 1 {
+		t.Fatalf("Sweep removed %d, want 1", n)
+	}
+	if len(reasons) != 1 || reasons[0] != ConversationTimedOut {
+		t.Fatalf("OnAbort reasons = %v, want [%s]", reasons, ConversationTimedOut)
+	}
+	if s, _ := m.Active(live); s == nil {
+		t.Fatal("Sweep removed a live conversation")
+	}
+
+	// Hết hạn khi user gửi tin: update đi tiếp như bình thường
+	state, _ = store.Get(live)
+	state.ExpiresAt = time.Now().Add(-time.Second)
+	_ = store.Set(live, state)
+	passed := false
+	handler := m.Middleware()(func(ctx context.Context, u *Update) error {
+		passed = true
+		return nil
+	})
+	if err := handler(ctx, textUpdate(1, 2, "hi")); err != nil {
+		t.Fatal(err)
+	}
+	if !passed || len(reasons) != 2 {
+		t.Fatalf("expired conversation: passed=%v reasons=%v", passed, reasons)
+	}
+}
+
+func TestFileConversationStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conv.json")
+	store, err := NewFileConversationStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := ConversationKey{ChatID: -100, UserID: 7}
+	if err := store.Set(key, &ConversationState{Flow: "signup", Step: "age", Data: map[string]string{"name": "Lan"}}); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewFileConversationStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := reloaded.Get(key)
+	if got == nil || got.Step != "age" || got.Data["name"] != "Lan" {
+		t.Fatalf("reloaded state = %+v", got)
+	}
+}
@@ -0,0 +1,169 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultSeenUpdatesLimit là số update_id gần nhất được nhớ để chống xử lý trùng
+const DefaultSeenUpdatesLimit = 10000
+
+// seenFlushDelay: các thay đổi trong khoảng này được gộp vào một lần ghi file
+const seenFlushDelay = time.Second
+
+// SeenUpdates nhớ các update_id đã nhận (tối đa limit cái gần nhất, cũ nhất bị bỏ trước).
+// Nếu có path thì ghi ra file JSON (gộp thay đổi, chậm nhất seenFlushDelay) nên vẫn chống trùng
+// được sau khi restart; gọi Close khi tắt để ghi nốt.
+type SeenUpdates struct {
+	path  string
+	limit int
+
+	mu       sync.Mutex
+	order    []int // theo thứ tự nhận, để bỏ cái cũ nhất khi đầy
+	ids      map[int]bool
+	dirty    bool
+	timer    *time.Timer // != nil khi đã hẹn một lần ghi
+	flushErr error       // lỗi của lần ghi nền gần nhất, trả về ở TryMark/Forget kế tiếp
+
+	saveMu sync.Mutex // chỉ một lần ghi file tại một thời điểm, không giữ mu khi ghi
+}
+
+// NewSeenUpdates mở (hoặc tạo mới) store tại path; path rỗng = chỉ giữ trong RAM
+func NewSeenUpdates(path string, limit int) (*SeenUpdates, error) {
+	if limit <= 0 {
+		limit = DefaultSeenUpdatesLimit
+	}
+	s := &SeenUpdates{path: path, limit: limit, ids: make(map[int]bool)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read seen updates: %v", err)
+	}
+	var order []int
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &order); err != nil {
+			return nil, fmt.Errorf("decode seen updates: %v", err)
+		}
+	}
+	if len(order) > limit {
+		order = order[len(order)-limit:]
+	}
+	for _, id := range order {
+		s.ids[id] = true
+	}
+	s.order = order
+	return s, nil
+}
+
+// TryMark đánh dấu id đã nhận; trả về false nếu id đã có từ trước (update bị gửi lại)
+func (s *SeenUpdates) TryMark(id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids[id] {
+		return false, nil
+	}
+	s.ids[id] = true
+	s.order = append(s.order, id)
+	if len(s.order) > s.limit {
+		drop := len(s.order) - s.limit
+		for _, old := range s.order[:drop] {
+			delete(s.ids, old)
+		}
+		s.order = append([]int(nil), s.order[drop:]...)
+	}
+	return true, s.scheduleFlushLocked()
+}
+
+// Forget bỏ đánh dấu id, vd khi không nhận xử lý được và muốn Telegram gửi lại
+func (s *SeenUpdates) Forget(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ids[id] {
+		return nil
+	}
+	delete(s.ids, id)
+	for i, v := range s.order {
+		if v == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return s.scheduleFlushLocked()
+}
+
+// scheduleFlushLocked đánh dấu cần ghi và hẹn một lần Flush nếu chưa có;
+// trả về (rồi xoá) lỗi của lần ghi nền trước đó để caller log
+func (s *SeenUpdates) scheduleFlushLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	s.dirty = true
+	if s.timer == nil {
+		s.timer = time.AfterFunc(seenFlushDelay, func() { _ = s.Flush() })
+	}
+	err := s.flushErr
+	s.flushErr = nil
+	return err
+}
+
+// Flush ghi ngay các thay đổi chưa lưu ra file
+func (s *SeenUpdates) Flush() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	s.dirty = false
+	order := append([]int(nil), s.order...)
+	s.mu.Unlock()
+
+	err := s.save(order)
+	if err != nil {
+		s.mu.Lock()
+		s.dirty = true // lần thay đổi sau sẽ thử ghi lại
+		s.flushErr = err
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// Close ghi nốt thay đổi còn chờ; gọi khi tắt server
+func (s *SeenUpdates) Close() error {
+	return s.Flush()
+}
+
+// save ghi ra file tạm rồi rename như FileIDCache
+func (s *SeenUpdates) save(order []int) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeenUpdatesTryMark(t *testing.T) {
+	s, err := NewSeenUpdates("", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		op    string
+		id    int
+		fresh bool
+	}{
+		{"mark", 1, true},
+		{"mark", 1, false},
+		{"mark", 2, true},
+		{"mark", 3, true},
+		{"mark", 4, true}, // đầy: bỏ 1
+		{"mark", 1, true},
+		{"mark", 4, false},
+		{"forget", 4, false},
+		{"mark", 4, true},
+	}
+	for i, st := range steps {
+		if st.op == "forget" {
+			if err := s.Forget(st.id); err != nil {
+				t.Fatalf("step %d: Forget(%d) error %v", i, st.id, err)
+			}
+			continue
+		}
+		fresh, err := s.TryMark(st.id)
+		if err != nil {
+			t.Fatalf("step %d: TryMark(%d) error %v", i, st.id, err)
+		}
+		if fresh != st.fresh {
+			t.Fatalf("step %d: TryMark(%d) = %v, want %v", i, st.id, fresh, st.fresh)
+		}
+	}
+}
+
+func TestSeenUpdatesPersistsOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	s, err := NewSeenUpdates(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{10, 11, 12} {
+		if _, err := s.TryMark(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = s.Forget(11)
+	// ghi được gộp lại, chưa có lần ghi nào ngay sau TryMark
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("store written synchronously, stat err = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewSeenUpdates(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[int]bool{10: false, 11: true, 12: false, 13: true} {
+		if fresh, _ := reloaded.TryMark(id); fresh != want {
+			t.Fatalf("after reload TryMark(%d) = %v, want %v", id, fresh, want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dnk.com/hoc-golang/middleware"
+)
+
+// Middleware bọc một UpdateHandlerFunc, vd để log, kiểm tra quyền hay recover panic
+type Middleware func(next UpdateHandlerFunc) UpdateHandlerFunc
+
+// Command là lệnh đã tách từ text "/name@bot arg1 "arg 2""
+type Command struct {
+	Name    string   // tên lệnh, chữ thường, không có "/" và "@bot"
+	Mention string   // phần sau "@" nếu có
+	RawArgs string   // toàn bộ phần sau tên lệnh
+	Args    []string // RawArgs tách theo khoảng trắng, giữ nguyên cụm trong ngoặc kép/đơn
+}
+
+// CommandHandlerFunc xử lý một lệnh
+type CommandHandlerFunc func(ctx context.Context, msg *Message, cmd Command) error
+
+// RegexHandlerFunc xử lý message có text khớp regex; match[0] là cả chuỗi khớp, match[i] là group i
+type RegexHandlerFunc func(ctx context.Context, msg *Message, match []string) error
+
+// CommandInfo mô tả lệnh đã đăng ký (dùng cho /help, setMyCommands)
+type CommandInfo struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+type commandRoute struct {
+	info    CommandInfo
+	handler UpdateHandlerFunc
+}
+
+type regexRoute struct {
+	re      *regexp.Regexp
+	handler RegexHandlerFunc
+	mws     []Middleware
+}
+
+// Dispatcher chuyển update tới handler theo thứ tự ưu tiên:
+// lệnh → regex → callback prefix → handler theo loại update → fallback.
+// Chỉ handler khớp đầu tiên được gọi. Middleware đăng ký bằng Use bọc toàn bộ quá trình này.
+type Dispatcher struct {
+	client *TelegramClient // dùng để trả lời callback query, có thể nil
+
+	// Callbacks nhận callback query theo prefix của callback_data
+	Callbacks *CallbackRouter
+
+	mu          sync.RWMutex
+	botUsername string
+	commands    map[string]commandRoute
+	regexes     []regexRoute
+	kinds       map[string]UpdateHandlerFunc
+	fallback    UpdateHandlerFunc
+	middlewares []Middleware
+}
+
+// NewDispatcher tạo dispatcher; client dùng để tự trả lời callback query không có handler
+func NewDispatcher(client *TelegramClient) *Dispatcher {
+	return &Dispatcher{
+		client:    client,
+		Callbacks: NewCallbackRouter(),
+		commands:  make(map[string]commandRoute),
+		kinds:     make(map[string]UpdateHandlerFunc),
+	}
+}
+
+// SetBotUsername đặt username của bot để nhận "/cmd@bot" và bỏ qua lệnh gửi cho bot khác trong group
+func (d *Dispatcher) SetBotUsername(username string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.botUsername = strings.TrimPrefix(username, "@")
+}
+
+// Use thêm middleware bọc mọi update; middleware thêm trước nằm ngoài cùng
+func (d *Dispatcher) Use(mws ...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.middlewares = append(d.middlewares, mws...)
+}
+
+var commandNameRe = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// OnCommand đăng ký handler cho "/name"; mws chỉ áp cho lệnh này (vd kiểm tra quyền admin)
+func (d *Dispatcher) OnCommand(name, description string, fn CommandHandlerFunc, mws ...Middleware) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	if !commandNameRe.MatchString(name) {
+		panic(fmt.Sprintf("telegram: invalid command name %q", name))
+	}
+	handler := func(ctx context.Context, u *Update) error {
+		cmd, _ := ParseCommand(u.Message.Text)
+		return fn(ctx, u.Message, cmd)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commands[name] = commandRoute{
+		info:    CommandInfo{Command: name, Description: description},
+		handler: chain(handler, mws),
+	}
+}
+
+// OnRegex đăng ký handler cho message có text khớp pattern, theo thứ tự đăng ký
+func (d *Dispatcher) OnRegex(pattern string, fn RegexHandlerFunc, mws ...Middleware) {
+	re := regexp.MustCompile(pattern)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.regexes = append(d.regexes, regexRoute{re: re, handler: fn, mws: mws})
+}
+
+// OnCallback đăng ký handler cho callback_data bắt đầu bằng prefix (xem CallbackRouter)
+func (d *Dispatcher) OnCallback(prefix string, fn CallbackHandlerFunc) {
+	d.Callbacks.Handle(prefix, fn)
+}
+
+// On đăng ký handler cho một loại update (UpdateMessage, UpdateCallbackQuery, UpdateChatMember...)
+// khi không có lệnh/regex/callback nào khớp
+func (d *Dispatcher) On(kind string, fn UpdateHandlerFunc, mws ...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.kinds[kind] = chain(fn, mws)
+}
+
+// OnFallback đăng ký handler cho update không khớp handler nào
+func (d *Dispatcher) OnFallback(fn UpdateHandlerFunc, mws ...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fallback = chain(fn, mws)
+}
+
+// Commands trả về các lệnh đã đăng ký, sắp theo tên
+func (d *Dispatcher) Commands() []CommandInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	infos := make([]CommandInfo, 0, len(d.commands))
+	for _, route := range d.commands {
+		infos = append(infos, route.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Command < infos[j].Command })
+	return infos
+}
+
+// Handle xử lý một update qua chuỗi middleware; dùng được làm UpdateHandlerFunc cho Poller
+func (d *Dispatcher) Handle(ctx context.Context, u *Update) error {
+	d.mu.RLock()
+	mws := d.middlewares
+	d.mu.RUnlock()
+	return chain(d.route, mws)(ctx, u)
+}
+
+// callbackErrorText là alert cố định hiện cho người bấm khi callback handler lỗi
+const callbackErrorText = "❌ Có lỗi xảy ra, vui lòng thử lại sau."
+
+func (d *Dispatcher) route(ctx context.Context, u *Update) error {
+	if u.Message != nil {
+		if handler := d.matchMessage(u.Message); handler != nil {
+			return handler(ctx, u)
+		}
+	}
+
+	if u.CallbackQuery != nil {
+		handled, err := d.Callbacks.Dispatch(ctx, u.CallbackQuery)
+		if err != nil {
+			// Báo lỗi cho người bấm thay vì để nút quay mãi; chi tiết lỗi chỉ ghi log (trả về cho caller),
+			// không gửi cho người dùng vì có thể lộ thông tin nội bộ
+			d.answerCallback(ctx, AnswerCallbackQueryRequest{
+				CallbackQueryID: u.CallbackQuery.ID,
+				Text:            callbackErrorText,
+				ShowAlert:       true,
+			})
+			return fmt.Errorf("callback %q failed: %w", u.CallbackQuery.Data, err)
+		}
+		if handled {
+			return nil
+		}
+	}
+
+	d.mu.RLock()
+	handler, ok := d.kinds[u.Kind()]
+	if !ok {
+		handler = d.fallback
+	}
+	d.mu.RUnlock()
+
+	if handler != nil {
+		return handler(ctx, u)
+	}
+	if u.CallbackQuery != nil {
+		// Không ai xử lý: vẫn trả lời để Telegram tắt loading trên nút
+		d.answerCallback(ctx, AnswerCallbackQueryRequest{CallbackQueryID: u.CallbackQuery.ID})
+	}
+	return nil
+}
+
+// matchMessage tìm handler lệnh hoặc regex cho message, nil nếu không khớp
+func (d *Dispatcher) matchMessage(msg *Message) UpdateHandlerFunc {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if cmd, ok := ParseCommand(msg.Text); ok {
+		// "/cmd@otherbot" trong group là gửi cho bot khác
+		if cmd.Mention == "" || d.botUsername == "" || strings.EqualFold(cmd.Mention, d.botUsername) {
+			if route, ok := d.commands[cmd.Name]; ok {
+				return route.handler
+			}
+		}
+	}
+
+	for _, r := range d.regexes {
+		if match := r.re.FindStringSubmatch(msg.Text); match != nil {
+			fn := r.handler
+			return chain(func(ctx context.Context, u *Update) error {
+				return fn(ctx, u.Message, match)
+			}, r.mws)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) answerCallback(ctx context.Context, req AnswerCallbackQueryRequest) {
+	if d.client == nil {
+		return
+	}
+	if _, _, err := d.client.AnswerCallbackQueryRawContext(ctx, req); err != nil {
+		middleware.LogTelegramError("⚠️ answerCallbackQuery failed", err, map[string]interface{}{"callback_query_id": req.CallbackQueryID})
+	}
+}
+
+// ParseCommand tách lệnh từ text; ok=false nếu text không bắt đầu bằng "/"
+func ParseCommand(text string) (Command, bool) {
+	if !strings.HasPrefix(text, "/") || len(text) < 2 {
+		return Command{}, false
+	}
+	head, rest, _ := strings.Cut(text[1:], " ")
+	if i := strings.IndexAny(head, "\n\t"); i >= 0 {
+		// "/cmd\narg" vẫn là lệnh cmd
+		head, rest = head[:i], head[i+1:]+" "+rest
+	}
+	name, mention, _ := strings.Cut(head, "@")
+	if name == "" {
+		return Command{}, false
+	}
+	rest = strings.TrimSpace(rest)
+	return Command{
+		Name:    strings.ToLower(name),
+		Mention: mention,
+		RawArgs: rest,
+		Args:    splitArgs(rest),
+	}, true
+}
+
+// splitArgs tách theo khoảng trắng, "..." hoặc '...' được giữ thành một tham số
+func splitArgs(s string) []string {
+	var args []string
+	var b strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+			inArg = true
+		case quote == 0 && (r == ' ' || r == '\t' || r == '\n'):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args
+}
+
+// chain bọc handler bằng mws, mws[0] nằm ngoài cùng
+func chain(handler UpdateHandlerFunc, mws []Middleware) UpdateHandlerFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
+
+// ---- Middleware dựng sẵn ----
+
+// LoggingMiddleware ghi mỗi update vào telegram.log: loại, người gửi, thời gian xử lý, lỗi
+func LoggingMiddleware() Middleware {
+	return func(next UpdateHandlerFunc) UpdateHandlerFunc {
+		return func(ctx context.Context, u *Update) error {
+			start := time.Now()
+			err := next(ctx, u)
+			fields := map[string]interface{}{
+				"update_id":   u.UpdateID,
+				"kind":        u.Kind(),
+				"duration_ms": time.Since(start).Milliseconds(),
+			}
+			if sender := u.Sender(); sender != nil {
+				fields["user_id"] = sender.ID
+				fields["username"] = sender.Username
+			}
+			if chat := u.Chat(); chat != nil {
+				fields["chat_id"] = chat.ID
+			}
+			if err != nil {
+				middleware.LogTelegramError("❌ Update failed", err, fields)
+			} else {
+				middleware.LogTelegramInfo("📨 Update handled", fields)
+			}
+			return err
+		}
+	}
+}
+
+// RecoveryMiddleware biến panic trong handler thành error để server/poller không bị sập
+func RecoveryMiddleware() Middleware {
+	return func(next UpdateHandlerFunc) UpdateHandlerFunc {
+		return func(ctx context.Context, u *Update) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					middleware.LogTelegramError("❌ Update handler panic", fmt.Errorf("%v", r), map[string]interface{}{
+						"update_id": u.UpdateID,
+						"stack":     string(debug.Stack()),
+					})
+					err = fmt.Errorf("panic while handling update %d: %v", u.UpdateID, r)
+				}
+			}()
+			return next(ctx, u)
+		}
+	}
+}
+
+// AuthMiddleware chỉ cho update được allow đi tiếp; update bị chặn được bỏ qua im lặng
+// (trả nil để Telegram/Poller không gửi lại)
+func AuthMiddleware(allow func(u *Update) bool) Middleware {
+	return func(next UpdateHandlerFunc) UpdateHandlerFunc {
+		return func(ctx context.Context, u *Update) error {
+			if !allow(u) {
+				fields := map[string]interface{}{"update_id": u.UpdateID}
+				if sender := u.Sender(); sender != nil {
+					fields["user_id"] = sender.ID
+				}
+				middleware.LogTelegramInfo("🚫 Update rejected by auth", fields)
+				return nil
+			}
+			return next(ctx, u)
+		}
+	}
+}
+
+// AllowUserIDs trả về hàm allow cho AuthMiddleware theo danh sách user ID
+func AllowUserIDs(ids ...int64) func(u *Update) bool {
+	allowed := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		allowed[id] = true
+	}
+	return func(u *Update) bool {
+		sender := u.Sender()
+		return sender != nil && allowed[int64(sender.ID)]
+	}
+}
@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		ok   bool
+		want Command
+	}{
+		{text: "hello", ok: false},
+		{text: "/", ok: false},
+		{text: "/@bot", ok: false},
+		{text: "/start", ok: true, want: Command{Name: "start"}},
+		{text: "/Start@MyBot", ok: true, want: Command{Name: "start", Mention: "MyBot"}},
+		{text: "/ban 123 1h", ok: true, want: Command{Name: "ban", RawArgs: "123 1h", Args: []string{"123", "1h"}}},
+		{text: "/say   a   b  ", ok: true, want: Command{Name: "say", RawArgs: "a   b", Args: []string{"a", "b"}}},
+		{text: `/say "xin chào" 'bạn ơi' x`, ok: true, want: Command{Name: "say", RawArgs: `"xin chào" 'bạn ơi' x`, Args: []string{"xin chào", "bạn ơi", "x"}}},
+		{text: `/say "it's"`, ok: true, want: Command{Name: "say", RawArgs: `"it's"`, Args: []string{"it's"}}},
+		{text: "/note\nline1 line2", ok: true, want: Command{Name: "note", RawArgs: "line1 line2", Args: []string{"line1", "line2"}}},
+		{text: `/say ""`, ok: true, want: Command{Name: "say", RawArgs: `""`, Args: []string{""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := ParseCommand(tt.text)
+			if ok != tt.ok {
+				t.Fatalf("ParseCommand(%q) ok = %v, want %v", tt.text, ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseCommand(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Các nhóm lỗi để dùng với errors.Is, vd: errors.Is(err, telegram.ErrFloodWait)
+var (
+	ErrFloodWait    = errors.New("telegram: too many requests (flood wait)")
+	ErrForbidden    = errors.New("telegram: forbidden")
+	ErrBotBlocked   = errors.New("telegram: bot was blocked by the user")
+	ErrChatNotFound = errors.New("telegram: chat not found")
+	ErrBadRequest   = errors.New("telegram: bad request")
+	ErrUnauthorized = errors.New("telegram: unauthorized (invalid token)")
+	ErrChatMigrated = errors.New("telegram: group migrated to supergroup")
+)
+
+// ResponseParameters là field "parameters" Telegram trả kèm khi lỗi
+type ResponseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+	RetryAfter      int   `json:"retry_after,omitempty"` // giây
+}
+
+// APIError là lỗi đã decode từ body {ok:false, error_code, description, parameters}
+type APIError struct {
+	Method      string              `json:"-"` // method Bot API, vd "sendMessage"
+	StatusCode  int                 `json:"-"` // HTTP status code
+	ErrorCode   int                 `json:"error_code"`
+	Description string              `json:"description"`
+	Parameters  *ResponseParameters `json:"parameters,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("❌ %s failed, error_code: %d, description: %s", e.Method, e.ErrorCode, e.Description)
+}
+
+// RetryAfter trả về thời gian Telegram yêu cầu chờ trước khi gửi lại (0 nếu không có)
+func (e *APIError) RetryAfter() time.Duration {
+	if e.Parameters == nil {
+		return 0
+	}
+	return time.Duration(e.Parameters.RetryAfter) * time.Second
+}
+
+// MigrateToChatID trả về chat_id mới khi group đã được nâng cấp thành supergroup
+func (e *APIError) MigrateToChatID() int64 {
+	if e.Parameters == nil {
+		return 0
+	}
+	return e.Parameters.MigrateToChatID
+}
+
+// Is cho phép so khớp APIError với các sentinel ErrXxx theo error_code/description
+func (e *APIError) Is(target error) bool {
+	desc := strings.ToLower(e.Description)
+	switch target {
+	case ErrFloodWait:
+		return e.ErrorCode == http.StatusTooManyRequests
+	case ErrForbidden:
+		return e.ErrorCode == http.StatusForbidden
+	case ErrBotBlocked:
+		return e.ErrorCode == http.StatusForbidden && strings.Contains(desc, "bot was blocked")
+	case ErrChatNotFound:
+		return e.ErrorCode == http.StatusBadRequest && strings.Contains(desc, "chat not found")
+	case ErrBadRequest:
+		return e.ErrorCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.ErrorCode == http.StatusUnauthorized
+	case ErrChatMigrated:
+		return e.MigrateToChatID() != 0
+	}
+	return false
+}
+
+// parseAPIError decode body lỗi của Telegram thành *APIError.
+// Nếu body không phải JSON hợp lệ thì dùng nguyên body làm description.
+func parseAPIError(method string, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{Method: method, StatusCode: statusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.ErrorCode == 0 {
+		apiErr.ErrorCode = statusCode
+		if apiErr.Description == "" {
+			apiErr.Description = strings.TrimSpace(string(body))
+		}
+	}
+	return apiErr
+}
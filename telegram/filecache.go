@@ -0,0 +1,145 @@
+package telegram
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileIDCache nhớ file_id Telegram trả về sau khi upload, key theo hash nội dung file,
+// để lần sau gửi lại cùng file chỉ cần gửi file_id thay vì upload lại.
+// Dữ liệu được ghi ra file JSON nên vẫn còn sau khi restart.
+type FileIDCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+// NewFileIDCache mở (hoặc tạo mới) cache lưu tại path
+func NewFileIDCache(path string) (*FileIDCache, error) {
+	cache := &FileIDCache{path: path, entries: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read file_id cache: %v", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &cache.entries); err != nil {
+			return nil, fmt.Errorf("decode file_id cache: %v", err)
+		}
+	}
+	return cache, nil
+}
+
+// WithFileIDCache bật cache file_id cho các method gửi media từ file local
+func WithFileIDCache(cache *FileIDCache) Option {
+	return func(c *TelegramClient) {
+		c.fileIDs = cache
+	}
+}
+
+func (f *FileIDCache) Get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id, ok := f.entries[key]
+	return id, ok
+}
+
+func (f *FileIDCache) Set(key, fileID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries[key] = fileID
+	return f.saveLocked()
+}
+
+func (f *FileIDCache) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.entries, key)
+	return f.saveLocked()
+}
+
+// saveLocked ghi ra file tạm rồi rename để không làm hỏng cache nếu crash giữa chừng
+func (f *FileIDCache) saveLocked() error {
+	data, err := json.MarshalIndent(f.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// fileCacheKey = sha256 nội dung + field (cùng một file gửi dạng photo và document có file_id khác nhau)
+func fileCacheKey(path, fieldName string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)) + ":" + fieldName, nil
+}
+
+// fileIDFromResponse lấy file_id của media vừa gửi trong body sendXxx.
+// Với photo Telegram trả về nhiều kích thước, lấy bản lớn nhất (cuối mảng).
+func fileIDFromResponse(body []byte, fieldName string) string {
+	var resp struct {
+		Result map[string]json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	raw, ok := resp.Result[fieldName]
+	if !ok {
+		return ""
+	}
+	if fieldName == "photo" {
+		var sizes []struct {
+			FileID string `json:"file_id"`
+		}
+		if json.Unmarshal(raw, &sizes) != nil || len(sizes) == 0 {
+			return ""
+		}
+		return sizes[len(sizes)-1].FileID
+	}
+	var media struct {
+		FileID string `json:"file_id"`
+	}
+	_ = json.Unmarshal(raw, &media)
+	return media.FileID
+}
+
+// isStaleFileID: Telegram không nhận file_id đã lưu (hết hạn, sai bot...) → cần upload lại
+func isStaleFileID(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != 400 {
+		return false
+	}
+	desc := strings.ToLower(apiErr.Description)
+	return strings.Contains(desc, "file identifier") ||
+		strings.Contains(desc, "file_id") ||
+		strings.Contains(desc, "file reference") ||
+		strings.Contains(desc, "wrong type")
+}
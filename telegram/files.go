@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GetFileContext lấy thông tin file (file_path) từ file_id. Link tải có hiệu lực ít nhất 1 giờ,
+// Bot API chính thức chỉ cho tải file tối đa 20 MB.
+func (c *TelegramClient) GetFileContext(ctx context.Context, fileID string) (*File, error) {
+	if fileID == "" {
+		return nil, fmt.Errorf("file_id is required")
+	}
+	respBody, _, err := c.postJSON(ctx, "getFile", map[string]interface{}{"file_id": fileID})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Ok     bool `json:"ok"`
+		Result File `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	if result.Result.FilePath == "" {
+		return nil, fmt.Errorf("getFile: file_path missing for %s", fileID)
+	}
+	return &result.Result, nil
+}
+
+func (c *TelegramClient) GetFile(fileID string) (*File, error) {
+	return c.GetFileContext(context.Background(), fileID)
+}
+
+// FileURL trả về URL tải file từ file_path. URL chứa bot token, không được trả ra ngoài.
+func (c *TelegramClient) FileURL(filePath string) string {
+	return fmt.Sprintf("%s/file/bot%s/%s", c.BaseURL, c.Token, strings.TrimPrefix(filePath, "/"))
+}
+
+// DownloadFileContext gọi getFile rồi stream nội dung file vào w, trả về thông tin file và số byte đã ghi
+func (c *TelegramClient) DownloadFileContext(ctx context.Context, fileID string, w io.Writer) (*File, int64, error) {
+	file, err := c.GetFileContext(ctx, fileID)
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := c.DownloadFilePathContext(ctx, file.FilePath, w)
+	return file, n, err
+}
+
+// DownloadFilePathContext stream file có file_path (từ getFile) vào w.
+// Không retry vì w có thể đã nhận một phần dữ liệu.
+func (c *TelegramClient) DownloadFilePathContext(ctx context.Context, filePath string, w io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.FileURL(filePath), nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("download %s error: %w", filePath, c.redactURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return 0, parseAPIError("downloadFile", resp.StatusCode, body)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("download %s error: %v", filePath, err)
+	}
+	return n, nil
+}
+
+func (c *TelegramClient) DownloadFile(fileID string, w io.Writer) (*File, int64, error) {
+	return c.DownloadFileContext(context.Background(), fileID, w)
+}
+
+func redactToken(s, token string) string {
+	if token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, token, "<token>")
+}
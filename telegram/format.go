@@ -0,0 +1,39 @@
+package telegram
+
+import "strings"
+
+var (
+	markdownV2Replacer = strings.NewReplacer(
+		`\`, `\\`, `_`, `\_`, `*`, `\*`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`,
+		`~`, `\~`, "`", "\\`", `>`, `\>`, `#`, `\#`, `+`, `\+`, `-`, `\-`, `=`, `\=`,
+		`|`, `\|`, `{`, `\{`, `}`, `\}`, `.`, `\.`, `!`, `\!`,
+	)
+	markdownV2CodeReplacer = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+	htmlReplacer           = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// EscapeMarkdownV2 escape text không tin cậy để chèn vào message parse_mode MarkdownV2
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+// EscapeMarkdownV2Code escape text đặt bên trong `code` hoặc ```pre``` của MarkdownV2
+func EscapeMarkdownV2Code(s string) string {
+	return markdownV2CodeReplacer.Replace(s)
+}
+
+// EscapeHTML escape text không tin cậy để chèn vào message parse_mode HTML
+func EscapeHTML(s string) string {
+	return htmlReplacer.Replace(s)
+}
+
+// Escape chọn cách escape theo parse_mode; parse_mode rỗng thì giữ nguyên
+func Escape(parseMode, s string) string {
+	switch parseMode {
+	case ParseModeHTML:
+		return EscapeHTML(s)
+	case ParseModeMarkdownV2:
+		return EscapeMarkdownV2(s)
+	}
+	return s
+}
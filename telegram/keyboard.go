@@ -0,0 +1,132 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ===== Inline keyboard (nút gắn dưới message) =====
+
+type InlineKeyboardButton struct {
+	Text                         string `json:"text"`
+	URL                          string `json:"url,omitempty"`
+	CallbackData                 string `json:"callback_data,omitempty"`
+	SwitchInlineQueryCurrentChat string `json:"switch_inline_query_current_chat,omitempty"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// NewInlineKeyboard tạo inline keyboard từ các hàng nút
+func NewInlineKeyboard(rows ...[]InlineKeyboardButton) *InlineKeyboardMarkup {
+	if rows == nil {
+		rows = [][]InlineKeyboardButton{} // Telegram cần [] chứ không phải null
+	}
+	return &InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// NewInlineRow gom các nút thành một hàng
+func NewInlineRow(buttons ...InlineKeyboardButton) []InlineKeyboardButton {
+	return buttons
+}
+
+// InlineButtonData tạo nút gửi callback_data về bot khi bấm
+func InlineButtonData(text, data string) InlineKeyboardButton {
+	return InlineKeyboardButton{Text: text, CallbackData: data}
+}
+
+// InlineButtonURL tạo nút mở link
+func InlineButtonURL(text, url string) InlineKeyboardButton {
+	return InlineKeyboardButton{Text: text, URL: url}
+}
+
+// Validate kiểm tra giới hạn của Telegram: callback_data 1-64 byte, mỗi nút có đúng một hành động
+func (m *InlineKeyboardMarkup) Validate() error {
+	for i, row := range m.InlineKeyboard {
+		for j, b := range row {
+			if b.Text == "" {
+				return fmt.Errorf("button [%d][%d]: text required", i, j)
+			}
+			if b.URL == "" && b.CallbackData == "" && b.SwitchInlineQueryCurrentChat == "" {
+				return fmt.Errorf("button [%d][%d]: url or callback_data required", i, j)
+			}
+			if len(b.CallbackData) > 64 {
+				return fmt.Errorf("button [%d][%d]: callback_data must be at most 64 bytes", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// ===== Reply keyboard (thay bàn phím của người dùng) =====
+
+type KeyboardButton struct {
+	Text            string `json:"text"`
+	RequestContact  bool   `json:"request_contact,omitempty"`
+	RequestLocation bool   `json:"request_location,omitempty"`
+}
+
+type ReplyKeyboardMarkup struct {
+	Keyboard              [][]KeyboardButton `json:"keyboard"`
+	IsPersistent          bool               `json:"is_persistent,omitempty"`
+	ResizeKeyboard        bool               `json:"resize_keyboard,omitempty"`
+	OneTimeKeyboard       bool               `json:"one_time_keyboard,omitempty"`
+	InputFieldPlaceholder string             `json:"input_field_placeholder,omitempty"`
+	Selective             bool               `json:"selective,omitempty"`
+}
+
+// NewReplyKeyboard tạo reply keyboard gọn (resize) từ các hàng nút
+func NewReplyKeyboard(rows ...[]KeyboardButton) *ReplyKeyboardMarkup {
+	return &ReplyKeyboardMarkup{Keyboard: rows, ResizeKeyboard: true}
+}
+
+// NewKeyboardRow tạo một hàng nút text
+func NewKeyboardRow(texts ...string) []KeyboardButton {
+	row := make([]KeyboardButton, 0, len(texts))
+	for _, t := range texts {
+		row = append(row, KeyboardButton{Text: t})
+	}
+	return row
+}
+
+type ReplyKeyboardRemove struct {
+	RemoveKeyboard bool `json:"remove_keyboard"`
+	Selective      bool `json:"selective,omitempty"`
+}
+
+// NewRemoveKeyboard ẩn reply keyboard đang hiển thị
+func NewRemoveKeyboard() *ReplyKeyboardRemove {
+	return &ReplyKeyboardRemove{RemoveKeyboard: true}
+}
+
+type ForceReply struct {
+	ForceReply            bool   `json:"force_reply"`
+	InputFieldPlaceholder string `json:"input_field_placeholder,omitempty"`
+	Selective             bool   `json:"selective,omitempty"`
+}
+
+// ValidateReplyMarkup kiểm tra reply_markup nhận từ API (có thể là map sau khi bind JSON).
+// Hiện chỉ kiểm tra sâu inline keyboard vì Telegram giới hạn callback_data.
+func ValidateReplyMarkup(markup interface{}) error {
+	if markup == nil {
+		return nil
+	}
+	if m, ok := markup.(*InlineKeyboardMarkup); ok {
+		return m.Validate()
+	}
+	raw, err := json.Marshal(markup)
+	if err != nil {
+		return fmt.Errorf("invalid reply_markup: %v", err)
+	}
+	var probe struct {
+		InlineKeyboard *[][]InlineKeyboardButton `json:"inline_keyboard"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return fmt.Errorf("invalid reply_markup: %v", err)
+	}
+	if probe.InlineKeyboard != nil {
+		return (&InlineKeyboardMarkup{InlineKeyboard: *probe.InlineKeyboard}).Validate()
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// InputMedia là một phần tử của album gửi qua sendMediaGroup
+type InputMedia struct {
+	Type      string `json:"type"`  // photo, video, audio, document
+	Media     string `json:"media"` // đường dẫn file local, URL hoặc file_id
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// MediaTypeFromFilename đoán type cho InputMedia theo đuôi file
+func MediaTypeFromFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return "photo"
+	case ".mp4", ".mov", ".webm":
+		return "video"
+	case ".mp3", ".m4a", ".ogg", ".flac", ".wav":
+		return "audio"
+	}
+	return "document"
+}
+
+// validateMediaGroup: album có 2-10 phần tử; photo/video trộn được với nhau,
+// còn audio và document chỉ đi cùng loại của chính nó
+func validateMediaGroup(items []InputMedia) error {
+	if len(items) < 2 || len(items) > 10 {
+		return fmt.Errorf("media group must contain 2-10 items, got %d", len(items))
+	}
+	kinds := map[string]bool{}
+	for i, it := range items {
+		switch it.Type {
+		case "photo", "video":
+			kinds["visual"] = true
+		case "audio", "document":
+			kinds[it.Type] = true
+		default:
+			return fmt.Errorf("item %d: unsupported media type %q", i, it.Type)
+		}
+		if it.Media == "" {
+			return fmt.Errorf("item %d: media required", i)
+		}
+	}
+	if len(kinds) > 1 {
+		return fmt.Errorf("audio and document items cannot be mixed with other media types")
+	}
+	return nil
+}
+
+func isRemoteMedia(media string) bool {
+	return strings.HasPrefix(media, "http://") || strings.HasPrefix(media, "https://")
+}
+
+// isLocalFile: không phải URL và tồn tại trên disk; còn lại coi như file_id
+func isLocalFile(media string) bool {
+	if isRemoteMedia(media) {
+		return false
+	}
+	info, err := os.Stat(media)
+	return err == nil && !info.IsDir()
+}
+
+// mediaAttachment là file local được gửi kèm qua attach://<field>
+type mediaAttachment struct {
+	field string
+	kind  string // loại media, dùng để tra giới hạn upload
+	path  string
+}
+
+// SendMediaGroupRawContext gửi album nhiều ảnh/video/file trong một message.
+// File local được upload qua multipart với media "attach://fileN"; URL và file_id gửi nguyên.
+func (c *TelegramClient) SendMediaGroupRawContext(ctx context.Context, chatID int64, items []InputMedia) ([]byte, int, error) {
+	if c.Token == "" {
+		return nil, 0, fmt.Errorf("⚠️ Telegram token empty")
+	}
+	if err := validateMediaGroup(items); err != nil {
+		return nil, 0, err
+	}
+
+	media := make([]InputMedia, len(items))
+	var attachments []mediaAttachment
+	for i, it := range items {
+		media[i] = it
+		if isLocalFile(it.Media) {
+			field := fmt.Sprintf("file%d", i)
+			attachments = append(attachments, mediaAttachment{field: field, kind: it.Type, path: it.Media})
+			media[i].Media = "attach://" + field
+		}
+	}
+
+	if len(attachments) == 0 {
+		return c.postJSON(ctx, "sendMediaGroup", map[string]interface{}{"chat_id": chatID, "media": media})
+	}
+
+	mediaJSON, err := json.Marshal(media)
+	if err != nil {
+		return nil, 0, fmt.Errorf("marshal error: %v", err)
+	}
+
+	parts := make([]uploadPart, 0, len(attachments))
+	for _, at := range attachments {
+		part, err := c.localUploadPart(at.field, at.kind, at.path)
+		if err != nil {
+			return nil, 0, err
+		}
+		parts = append(parts, part)
+	}
+	fields := map[string]string{"chat_id": strconv.FormatInt(chatID, 10), "media": string(mediaJSON)}
+	return c.postMultipart(ctx, "sendMediaGroup", chatID, fields, parts, true)
+}
+
+func (c *TelegramClient) SendMediaGroupRaw(chatID int64, items []InputMedia) ([]byte, int, error) {
+	return c.SendMediaGroupRawContext(context.Background(), chatID, items)
+}
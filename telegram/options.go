@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL là địa chỉ Bot API chính thức của Telegram
+const DefaultBaseURL = "https://api.telegram.org"
+
+// Option cấu hình TelegramClient theo kiểu functional options
+type Option func(*TelegramClient)
+
+// WithBaseURL trỏ client tới Bot API server khác (self-hosted hoặc server giả lập khi test)
+func WithBaseURL(baseURL string) Option {
+	return func(c *TelegramClient) {
+		if baseURL != "" {
+			c.BaseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithHTTPClient dùng http.Client do caller cung cấp cho mọi request
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *TelegramClient) {
+		if hc != nil {
+			c.Client = hc
+		}
+	}
+}
+
+// WithTimeout đặt timeout tổng cho mỗi request HTTP
+func WithTimeout(d time.Duration) Option {
+	return func(c *TelegramClient) {
+		c.timeout = d
+	}
+}
+
+// WithUserAgent đặt header User-Agent gửi kèm mỗi request
+func WithUserAgent(ua string) Option {
+	return func(c *TelegramClient) {
+		c.UserAgent = ua
+	}
+}
+
+// WithProxy gửi mọi request qua proxy (http, https hoặc socks5)
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *TelegramClient) {
+		c.proxy = proxyURL
+	}
+}
+
+// applyTransportOptions áp timeout/proxy lên http.Client.
+// Client do caller truyền vào được copy để không sửa đổi object dùng chung.
+func (c *TelegramClient) applyTransportOptions() {
+	if c.timeout == 0 && c.proxy == nil {
+		return
+	}
+	hc := *c.Client
+	if c.timeout > 0 {
+		hc.Timeout = c.timeout
+	}
+	if c.proxy != nil {
+		var tr *http.Transport
+		if base, ok := hc.Transport.(*http.Transport); ok && base != nil {
+			tr = base.Clone()
+		} else {
+			tr = http.DefaultTransport.(*http.Transport).Clone()
+		}
+		tr.Proxy = http.ProxyURL(c.proxy)
+		hc.Transport = tr
+	}
+	c.Client = &hc
+}
@@ -0,0 +1,282 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"dnk.com/hoc-golang/middleware"
+)
+
+// UpdateHandlerFunc xử lý một update, dùng chung cho webhook và long polling
+type UpdateHandlerFunc func(ctx context.Context, update *Update) error
+
+// OffsetStore lưu offset getUpdates để restart không đọc lại update đã xử lý
+type OffsetStore interface {
+	LoadOffset() (int, error)
+	SaveOffset(offset int) error
+}
+
+// FileOffsetStore lưu offset vào file JSON {"offset": N}
+type FileOffsetStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileOffsetStore(path string) *FileOffsetStore {
+	return &FileOffsetStore{path: path}
+}
+
+func (f *FileOffsetStore) LoadOffset() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read offset file: %v", err)
+	}
+	var state struct {
+		Offset int `json:"offset"`
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return 0, fmt.Errorf("decode offset file: %v", err)
+		}
+	}
+	return state.Offset, nil
+}
+
+// SaveOffset ghi ra file tạm rồi rename như FileIDCache
+func (f *FileOffsetStore) SaveOffset(offset int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := json.Marshal(map[string]int{"offset": offset})
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// PollerOptions cấu hình long polling
+type PollerOptions struct {
+	Timeout        int         // giây chờ mỗi lần getUpdates (long poll), mặc định 50
+	Limit          int         // số update tối đa mỗi lần, mặc định 100
+	AllowedUpdates []string    // nil = mọi loại trừ chat_member, message_reaction...
+	Store          OffsetStore // nil = chỉ nhớ offset trong RAM
+	DeleteWebhook  bool        // gọi deleteWebhook trước khi poll (getUpdates không chạy khi còn webhook)
+	Backoff        RetryPolicy // delay khi getUpdates lỗi, mặc định DefaultRetryPolicy
+}
+
+// Poller gọi getUpdates liên tục và chuyển từng update cho handler
+type Poller struct {
+	client  *TelegramClient
+	handler UpdateHandlerFunc
+	opts    PollerOptions
+
+	mu     sync.Mutex
+	offset int
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPoller tạo poller; gọi Start để chạy nền hoặc Run để chạy trong goroutine hiện tại
+func NewPoller(client *TelegramClient, handler UpdateHandlerFunc, opts PollerOptions) *Poller {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 50
+	}
+	if opts.Limit <= 0 || opts.Limit > 100 {
+		opts.Limit = 100
+	}
+	if opts.Backoff.MaxAttempts == 0 {
+		opts.Backoff = DefaultRetryPolicy()
+	}
+	// http.Client.Timeout áp cho cả request: long poll phải kết thúc trước nó.
+	// Tối thiểu 1s, timeout 0 thành short poll liên tục không nghỉ
+	if t := client.Client.Timeout; t > 0 && time.Duration(opts.Timeout)*time.Second >= t-5*time.Second {
+		opts.Timeout = max(int((t-5*time.Second)/time.Second), 1)
+	}
+	return &Poller{client: client, handler: handler, opts: opts}
+}
+
+// Offset trả về offset sẽ dùng cho lần getUpdates tiếp theo
+func (p *Poller) Offset() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.offset
+}
+
+// Start chạy Run trong goroutine riêng; gọi lại khi đang chạy sẽ báo lỗi
+func (p *Poller) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		return fmt.Errorf("poller already running")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		if err := p.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			middleware.LogTelegramError("❌ Poller stopped", err, nil)
+		}
+	}()
+	return nil
+}
+
+// Stop huỷ lần poll đang chờ và đợi goroutine của Start kết thúc
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.cancel, p.done = nil, nil
+	p.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Run poll tới khi ctx bị huỷ. Lỗi deleteWebhook/getUpdates được backoff rồi thử lại;
+// chỉ dừng hẳn khi token sai (401) vì thử lại cũng vô ích.
+func (p *Poller) Run(ctx context.Context) error {
+	if p.opts.Store != nil {
+		offset, err := p.opts.Store.LoadOffset()
+		if err != nil {
+			// Telegram vẫn giữ các update chưa xác nhận: poll từ 0 chỉ nhận lại lô cuối chưa kịp xác nhận
+			middleware.LogTelegramError("⚠️ Cannot load getUpdates offset, starting from 0", err, nil)
+		}
+		p.mu.Lock()
+		p.offset = offset
+		p.mu.Unlock()
+	}
+	if p.opts.DeleteWebhook {
+		for failures := 0; ; failures++ {
+			_, _, err := p.client.DeleteWebhookRawContext(ctx, false)
+			if err == nil {
+				break
+			}
+			if err := p.waitRetry(ctx, "deleteWebhook", failures, err); err != nil {
+				return fmt.Errorf("deleteWebhook before polling: %w", err)
+			}
+		}
+	}
+
+	middleware.LogTelegramInfo("▶️ Poller started", map[string]interface{}{"offset": p.Offset(), "timeout": p.opts.Timeout})
+	failures := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		updates, err := p.client.GetUpdatesV2Context(ctx, GetUpdatesParams{
+			Offset:         p.Offset(),
+			Limit:          p.opts.Limit,
+			Timeout:        p.opts.Timeout,
+			AllowedUpdates: p.opts.AllowedUpdates,
+		})
+		if err != nil {
+			if err := p.waitRetry(ctx, "getUpdates", failures, err); err != nil {
+				return err
+			}
+			failures++
+			continue
+		}
+		failures = 0
+
+		for i := range updates {
+			p.handle(ctx, &updates[i])
+		}
+		if len(updates) > 0 {
+			p.commit(updates[len(updates)-1].UpdateID + 1)
+		}
+	}
+}
+
+// waitRetry log lỗi của method rồi chờ theo backoff; trả lỗi khi không nên thử lại (ctx huỷ, token sai)
+func (p *Poller) waitRetry(ctx context.Context, method string, failures int, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	delay := p.errorDelay(failures, err)
+	middleware.LogTelegramError("⚠️ "+method+" failed, backing off", err, map[string]interface{}{
+		"failures": failures + 1,
+		"retry_in": delay.String(),
+	})
+	if !sleepContext(ctx, delay) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// errorDelay: 409 (còn webhook hoặc process khác đang poll) chờ lâu hơn, còn lại theo backoff
+func (p *Poller) errorDelay(failures int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if d := apiErr.RetryAfter(); d > 0 {
+			return d
+		}
+		if apiErr.ErrorCode == http.StatusConflict && strings.Contains(strings.ToLower(apiErr.Description), "webhook") {
+			failures = 16 // chờ tối đa MaxDelay
+		}
+	}
+	if d := p.opts.Backoff.backoff(min(failures, 16)); d > 0 {
+		return d
+	}
+	return time.Second
+}
+
+// handle gọi handler, lỗi/panic chỉ được log để một update hỏng không chặn cả hàng đợi
+func (p *Poller) handle(ctx context.Context, update *Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			middleware.LogTelegramError("❌ Update handler panic", fmt.Errorf("%v", r), map[string]interface{}{"update_id": update.UpdateID})
+		}
+	}()
+	if err := p.handler(ctx, update); err != nil {
+		middleware.LogTelegramError("❌ Update handler failed", err, map[string]interface{}{"update_id": update.UpdateID})
+	}
+}
+
+func (p *Poller) commit(offset int) {
+	p.mu.Lock()
+	p.offset = offset
+	p.mu.Unlock()
+	if p.opts.Store != nil {
+		if err := p.opts.Store.SaveOffset(offset); err != nil {
+			middleware.LogTelegramError("⚠️ Cannot save getUpdates offset", err, map[string]interface{}{"offset": offset})
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
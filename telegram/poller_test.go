@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPollerRetriesDeleteWebhook(t *testing.T) {
+	var deleteCalls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/deleteWebhook"):
+			if deleteCalls.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":502,"description":"Bad Gateway"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			_, _ = w.Write([]byte(`{"ok":true,"result":[{"update_id":5,"message":{"message_id":1,"chat":{"id":1},"text":"hi"}}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := NewTelegramClient("TOKEN", WithBaseURL(srv.URL), WithoutRateLimit(), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var got atomic.Int32
+	p := NewPoller(client, func(ctx context.Context, u *Update) error {
+		got.Store(int32(u.UpdateID))
+		cancel()
+		return nil
+	}, PollerOptions{
+		DeleteWebhook: true,
+		Backoff:       RetryPolicy{MaxAttempts: 1, BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond},
+	})
+
+	_ = p.Run(ctx)
+	if deleteCalls.Load() != 2 || got.Load() != 5 {
+		t.Fatalf("deleteWebhook calls = %d, update = %d; want 2 calls and update 5", deleteCalls.Load(), got.Load())
+	}
+}
+
+func TestPollerStopsOnUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+	}))
+	defer srv.Close()
+
+	client := NewTelegramClient("TOKEN", WithBaseURL(srv.URL), WithoutRateLimit())
+	p := NewPoller(client, func(ctx context.Context, u *Update) error { return nil }, PollerOptions{DeleteWebhook: true})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := p.Run(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Run error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestNewPollerClampsTimeout(t *testing.T) {
+	client := NewTelegramClient("TOKEN", WithTimeout(3*time.Second))
+	p := NewPoller(client, nil, PollerOptions{})
+	if p.opts.Timeout != 1 {
+		t.Fatalf("timeout = %d, want 1", p.opts.Timeout)
+	}
+}
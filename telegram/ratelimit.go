@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimits là giới hạn gửi tin của Telegram:
+// ~30 msg/s toàn bot, ~1 msg/s mỗi chat riêng, ~20 msg/phút mỗi group
+type RateLimits struct {
+	Global       rate.Limit
+	GlobalBurst  int
+	Private      rate.Limit
+	PrivateBurst int
+	Group        rate.Limit
+	GroupBurst   int
+}
+
+// DefaultRateLimits trả về giới hạn theo khuyến nghị của Telegram
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Global:       30,
+		GlobalBurst:  30,
+		Private:      1,
+		PrivateBurst: 1,
+		Group:        rate.Every(3 * time.Second),
+		GroupBurst:   5,
+	}
+}
+
+// WithRateLimits đổi giới hạn gửi tin mặc định
+func WithRateLimits(l RateLimits) Option {
+	return func(c *TelegramClient) {
+		c.limiter = newOutboundLimiter(l)
+	}
+}
+
+// WithoutRateLimit tắt hẳn việc throttle (vd khi test với server giả lập)
+func WithoutRateLimit() Option {
+	return func(c *TelegramClient) {
+		c.limiter = nil
+	}
+}
+
+type chatLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// outboundLimiter xếp hàng các request gửi tin thay vì để Telegram trả 429.
+// Mỗi request chờ limiter của chat trước rồi mới lấy token global, nên một chat
+// gửi dồn dập chỉ tự làm chậm chính nó, các chat khác vẫn được chia token global.
+type outboundLimiter struct {
+	limits RateLimits
+	global *rate.Limiter
+
+	mu        sync.Mutex
+	chats     map[int64]*chatLimiter
+	lastSweep time.Time
+}
+
+func newOutboundLimiter(l RateLimits) *outboundLimiter {
+	return &outboundLimiter{
+		limits:    l,
+		global:    rate.NewLimiter(l.Global, l.GlobalBurst),
+		chats:     make(map[int64]*chatLimiter),
+		lastSweep: time.Now(),
+	}
+}
+
+func (o *outboundLimiter) chat(chatID int64) *rate.Limiter {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	// Dọn limiter của các chat lâu không gửi
+	if now.Sub(o.lastSweep) > time.Minute {
+		for id, cl := range o.chats {
+			if now.Sub(cl.lastSeen) > 10*time.Minute {
+				delete(o.chats, id)
+			}
+		}
+		o.lastSweep = now
+	}
+
+	cl, ok := o.chats[chatID]
+	if !ok {
+		// chat_id âm là group/supergroup/channel
+		limiter := rate.NewLimiter(o.limits.Private, o.limits.PrivateBurst)
+		if chatID < 0 {
+			limiter = rate.NewLimiter(o.limits.Group, o.limits.GroupBurst)
+		}
+		cl = &chatLimiter{limiter: limiter}
+		o.chats[chatID] = cl
+	}
+	cl.lastSeen = now
+	return cl.limiter
+}
+
+// wait chặn tới khi được phép gửi, hoặc ctx bị huỷ
+func (o *outboundLimiter) wait(ctx context.Context, chatID int64) error {
+	if chatID != 0 {
+		if err := o.chat(chatID).Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return o.global.Wait(ctx)
+}
+
+// isOutboundMessage: các method tạo message mới, bị Telegram tính vào giới hạn gửi tin
+func isOutboundMessage(method string) bool {
+	return strings.HasPrefix(method, "send") || method == "forwardMessage" || method == "copyMessage"
+}
+
+// throttle chờ limiter nếu method là gửi tin; client không có limiter thì bỏ qua
+func (c *TelegramClient) throttle(ctx context.Context, method string, chatID int64) error {
+	if c.limiter == nil || !isOutboundMessage(method) {
+		return nil
+	}
+	return c.limiter.wait(ctx, chatID)
+}
+
+// chatIDFromValue lấy chat_id dạng int64 từ payload (số hoặc chuỗi số)
+func chatIDFromValue(v interface{}) int64 {
+	switch id := v.(type) {
+	case int64:
+		return id
+	case int:
+		return int64(id)
+	case float64:
+		return int64(id)
+	case string:
+		n, _ := strconv.ParseInt(id, 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,153 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"dnk.com/hoc-golang/middleware"
+)
+
+// RetryPolicy cấu hình việc tự gửi lại request khi Telegram lỗi tạm thời
+type RetryPolicy struct {
+	MaxAttempts   int           // tổng số lần gọi, 1 = không retry
+	BaseDelay     time.Duration // delay cho lần retry đầu, nhân đôi mỗi lần
+	MaxDelay      time.Duration // trần của backoff
+	MaxRetryAfter time.Duration // retry_after lớn hơn mức này thì bỏ cuộc luôn
+}
+
+// DefaultRetryPolicy: 3 lần gọi, backoff 1s → 2s (có jitter), chờ flood tối đa 60s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     time.Second,
+		MaxDelay:      30 * time.Second,
+		MaxRetryAfter: 60 * time.Second,
+	}
+}
+
+// WithRetryPolicy thay policy retry mặc định của client
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *TelegramClient) {
+		if p.MaxAttempts < 1 {
+			p.MaxAttempts = 1
+		}
+		c.retry = p
+	}
+}
+
+// WithoutRetry trả về bản sao của client (dùng chung http.Client, rate limiter, cache file_id)
+// chỉ gọi mỗi request một lần; dùng khi caller đã tự retry để số lần gửi không bị nhân lên
+func (c *TelegramClient) WithoutRetry() *TelegramClient {
+	cp := *c
+	cp.retry.MaxAttempts = 1
+	return &cp
+}
+
+// backoff tính delay cho lần retry thứ attempt (bắt đầu từ 0), có jitter trong [d/2, d]
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// retryDelay quyết định có retry lỗi err hay không, và chờ bao lâu
+func (p RetryPolicy) retryDelay(method string, attempt int, err error) (time.Duration, bool) {
+	if attempt+1 >= p.MaxAttempts {
+		return 0, false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.ErrorCode == http.StatusTooManyRequests:
+			wait := apiErr.RetryAfter()
+			if wait == 0 {
+				wait = p.backoff(attempt)
+			}
+			if p.MaxRetryAfter > 0 && wait > p.MaxRetryAfter {
+				return 0, false
+			}
+			return wait, true
+		case apiErr.StatusCode >= 500:
+			return p.backoff(attempt), true
+		default:
+			// 400/401/403/404... gửi lại cũng không khác
+			return 0, false
+		}
+	}
+
+	// Lỗi mạng: chỉ retry method idempotent, hoặc khi chưa kết nối được (request chưa tới Telegram)
+	var opErr *net.OpError
+	if isIdempotent(method) || (errors.As(err, &opErr) && opErr.Op == "dial") {
+		return p.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// isIdempotent: gọi lại nhiều lần không tạo thêm message/link mới
+func isIdempotent(method string) bool {
+	for _, prefix := range []string{"get", "edit", "delete", "pin", "unpin", "ban", "unban", "set", "answer"} {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// do gửi request với retry theo c.retry. newReq được gọi lại mỗi lần để có body mới.
+// Mỗi lần gửi đều đi qua rate limiter của chatID (0 = chỉ tính giới hạn global).
+func (c *TelegramClient) do(ctx context.Context, method string, chatID int64, newReq func() (*http.Request, error)) ([]byte, int, error) {
+	return c.doWithPolicy(ctx, method, chatID, c.retry, newReq)
+}
+
+// doWithPolicy giống do nhưng dùng policy riêng cho lần gọi này (vd body chỉ đọc được một lần)
+func (c *TelegramClient) doWithPolicy(ctx context.Context, method string, chatID int64, policy RetryPolicy, newReq func() (*http.Request, error)) ([]byte, int, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.throttle(ctx, method, chatID); err != nil {
+			return nil, 0, fmt.Errorf("%s rate limit wait: %w", method, err)
+		}
+		req, err := newReq()
+		if err != nil {
+			return nil, 0, err
+		}
+		body, status, err := c.doOnce(method, req)
+		if err == nil {
+			return body, status, nil
+		}
+
+		delay, ok := policy.retryDelay(method, attempt, err)
+		if !ok {
+			return body, status, err
+		}
+		middleware.LogTelegramError("⚠️ Telegram request failed, retrying", err, map[string]interface{}{
+			"method":   method,
+			"attempt":  attempt + 1,
+			"max":      policy.MaxAttempts,
+			"status":   status,
+			"retry_in": delay.String(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return body, status, err
+		case <-timer.C:
+		}
+	}
+}
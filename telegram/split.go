@@ -0,0 +1,335 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxMessageLength là số ký tự tối đa (tính theo UTF-16 như Telegram) của một tin nhắn
+const MaxMessageLength = 4096
+
+// Các parse_mode Telegram hỗ trợ
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+)
+
+// closerReserve chừa chỗ cho các thẻ/ký hiệu đóng thêm vào cuối mỗi phần
+const closerReserve = 64
+
+// numberReserve chừa chỗ cho nhãn "(i/n)" khi đánh số
+const numberReserve = 16
+
+// SplitOptions cấu hình cách chia text dài
+type SplitOptions struct {
+	Limit     int    // số ký tự tối đa mỗi phần, mặc định MaxMessageLength
+	ParseMode string // ParseModeHTML/ParseModeMarkdownV2: đóng/mở lại định dạng ở chỗ cắt
+	Numbered  bool   // thêm nhãn "(i/n)" ở đầu mỗi phần khi có nhiều hơn 1 phần
+}
+
+// SplitMessage chia text thành các phần vừa giới hạn của Telegram.
+// Ưu tiên cắt ở ranh giới đoạn, rồi dòng, rồi khoảng trắng, cuối cùng là ranh giới rune
+// (không bao giờ cắt đôi một ký tự UTF-8). Với parse_mode, thẻ HTML / ký hiệu MarkdownV2
+// đang mở sẽ được đóng ở cuối phần trước và mở lại ở đầu phần sau.
+func SplitMessage(text string, opts SplitOptions) []string {
+	limit := opts.Limit
+	if limit <= 0 || limit > MaxMessageLength {
+		limit = MaxMessageLength
+	}
+	if utf16Len(text) <= limit {
+		return []string{text}
+	}
+
+	state := &formatState{mode: opts.ParseMode}
+	var parts []string
+	rest := text
+	for rest != "" {
+		prefix := state.openers()
+		budget := limit - utf16Len(prefix)
+		if opts.Numbered {
+			budget -= numberReserve
+		}
+		if opts.ParseMode != "" {
+			budget -= closerReserve
+		}
+		if budget < limit/4 {
+			budget = limit / 4
+		}
+		if budget < 1 {
+			budget = 1
+		}
+
+		if utf16Len(rest) <= budget {
+			parts = append(parts, prefix+rest)
+			break
+		}
+
+		end, next := findCut(rest, budget, opts.ParseMode)
+		chunk := rest[:end]
+		rest = rest[next:]
+		state.scan(chunk)
+		parts = append(parts, prefix+chunk+state.closers())
+	}
+
+	if opts.Numbered && len(parts) > 1 {
+		for i := range parts {
+			parts[i] = numberLabel(i+1, len(parts), opts.ParseMode) + parts[i]
+		}
+	}
+	return parts
+}
+
+// findCut tìm vị trí cắt trong s sao cho phần đầu không vượt budget.
+// end là chỗ kết thúc phần hiện tại, next là chỗ bắt đầu phần sau (bỏ qua ký tự phân tách).
+func findCut(s string, budget int, parseMode string) (int, int) {
+	maxIdx := byteIndexForUTF16(s, budget)
+	if maxIdx == 0 {
+		// budget nhỏ hơn cả ký tự đầu (vd emoji 2 unit): vẫn lấy một rune để vòng lặp luôn tiến
+		_, size := utf8.DecodeRuneInString(s)
+		return size, size
+	}
+	window := s[:maxIdx]
+	end, next := maxIdx, maxIdx
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		// Không cắt quá sớm để tránh các phần quá ngắn
+		if i := strings.LastIndex(window, sep); i > maxIdx/2 {
+			end, next = i, i+len(sep)
+			break
+		}
+	}
+
+	safe := safeCut(s, end, parseMode)
+	if safe <= 0 {
+		return maxIdx, maxIdx
+	}
+	if safe < end {
+		return safe, safe
+	}
+	return end, next
+}
+
+// safeCut lùi vị trí cắt để không rơi vào giữa thẻ HTML, entity (&amp;) hay escape MarkdownV2
+func safeCut(s string, end int, parseMode string) int {
+	switch parseMode {
+	case ParseModeHTML:
+		head := s[:end]
+		if lt := strings.LastIndex(head, "<"); lt > strings.LastIndex(head, ">") {
+			end = lt
+			head = s[:end]
+		}
+		if amp := strings.LastIndex(head, "&"); amp > strings.LastIndex(head, ";") && end-amp < 10 {
+			end = amp
+		}
+	case ParseModeMarkdownV2:
+		backslashes := 0
+		for i := end - 1; i >= 0 && s[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			end--
+		}
+		// không tách đôi ``` , __ , ||
+		for end > 0 && end < len(s) && s[end-1] == s[end] && strings.IndexByte("`_|", s[end]) >= 0 {
+			end--
+		}
+	}
+	return end
+}
+
+func numberLabel(i, n int, parseMode string) string {
+	if parseMode == ParseModeMarkdownV2 {
+		return fmt.Sprintf("\\(%d/%d\\)\n", i, n)
+	}
+	return fmt.Sprintf("(%d/%d)\n", i, n)
+}
+
+// ---- Theo dõi định dạng đang mở giữa các phần ----
+
+type formatMark struct {
+	name   string // tên thẻ HTML hoặc ký hiệu MarkdownV2
+	opener string // chuỗi để mở lại ở phần sau
+}
+
+type formatState struct {
+	mode  string
+	stack []formatMark
+}
+
+var htmlTagRe = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)[^>]*>`)
+
+func (f *formatState) scan(chunk string) {
+	switch f.mode {
+	case ParseModeHTML:
+		f.scanHTML(chunk)
+	case ParseModeMarkdownV2:
+		f.scanMarkdownV2(chunk)
+	}
+}
+
+func (f *formatState) scanHTML(chunk string) {
+	for _, m := range htmlTagRe.FindAllStringSubmatch(chunk, -1) {
+		name := strings.ToLower(m[2])
+		if m[1] == "" {
+			f.stack = append(f.stack, formatMark{name: name, opener: m[0]})
+			continue
+		}
+		f.pop(name)
+	}
+}
+
+func (f *formatState) scanMarkdownV2(chunk string) {
+	for i := 0; i < len(chunk); i++ {
+		top := ""
+		if len(f.stack) > 0 {
+			top = f.stack[len(f.stack)-1].name
+		}
+		rest := chunk[i:]
+
+		// Trong code block / inline code chỉ tìm ký hiệu đóng
+		if top == "```" {
+			if strings.HasPrefix(rest, "```") {
+				f.pop("```")
+				i += 2
+			} else if chunk[i] == '\\' {
+				i++
+			}
+			continue
+		}
+		if top == "`" {
+			if chunk[i] == '`' {
+				f.pop("`")
+			} else if chunk[i] == '\\' {
+				i++
+			}
+			continue
+		}
+
+		switch {
+		case chunk[i] == '\\':
+			i++
+		case strings.HasPrefix(rest, "```"):
+			opener := "```"
+			if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+				opener = rest[:nl+1] // giữ cả tên ngôn ngữ, vd "```go\n"
+			}
+			f.stack = append(f.stack, formatMark{name: "```", opener: opener})
+			i += len(opener) - 1
+		case chunk[i] == '`':
+			f.stack = append(f.stack, formatMark{name: "`", opener: "`"})
+		case strings.HasPrefix(rest, "__"), strings.HasPrefix(rest, "||"):
+			f.toggle(rest[:2])
+			i++
+		case chunk[i] == '*', chunk[i] == '_', chunk[i] == '~':
+			f.toggle(rest[:1])
+		}
+	}
+}
+
+func (f *formatState) toggle(mark string) {
+	if !f.pop(mark) {
+		f.stack = append(f.stack, formatMark{name: mark, opener: mark})
+	}
+}
+
+// pop đóng mark gần nhất có tên name (cùng các mark lồng bên trong nó)
+func (f *formatState) pop(name string) bool {
+	for i := len(f.stack) - 1; i >= 0; i-- {
+		if f.stack[i].name == name {
+			f.stack = f.stack[:i]
+			return true
+		}
+	}
+	return false
+}
+
+func (f *formatState) openers() string {
+	var b strings.Builder
+	for _, m := range f.stack {
+		b.WriteString(m.opener)
+	}
+	return b.String()
+}
+
+func (f *formatState) closers() string {
+	var b strings.Builder
+	for i := len(f.stack) - 1; i >= 0; i-- {
+		m := f.stack[i]
+		switch {
+		case f.mode == ParseModeHTML:
+			b.WriteString("</" + m.name + ">")
+		case m.name == "```":
+			b.WriteString("\n```")
+		default:
+			b.WriteString(m.name)
+		}
+	}
+	return b.String()
+}
+
+// ---- Đếm độ dài theo UTF-16 như Telegram ----
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}
+
+// byteIndexForUTF16 trả về vị trí byte lớn nhất (ở ranh giới rune) mà s[:idx] không vượt quá units
+func byteIndexForUTF16(s string, units int) int {
+	n := 0
+	for i, r := range s {
+		w := 1
+		if r >= 0x10000 {
+			w = 2
+		}
+		if n+w > units {
+			return i
+		}
+		n += w
+	}
+	return len(s)
+}
+
+// splitRequest chia text của req; trả về từng phần cùng entities tương ứng.
+// Khi có entities thì không dùng parse_mode (Telegram bỏ qua parse_mode), nên các phần
+// là chuỗi con nguyên vẹn của text và entities được dời offset theo từng phần.
+func splitRequest(req SendMessageRequest) ([]string, [][]MessageEntity) {
+	if len(req.Entities) == 0 {
+		parts := SplitMessage(req.Text, SplitOptions{ParseMode: req.ParseMode, Numbered: true})
+		return parts, make([][]MessageEntity, len(parts))
+	}
+
+	parts := SplitMessage(req.Text, SplitOptions{Limit: MaxMessageLength - numberReserve})
+	if len(parts) == 1 {
+		return parts, [][]MessageEntity{req.Entities}
+	}
+
+	entities := make([][]MessageEntity, len(parts))
+	search := 0
+	for i, part := range parts {
+		idx := search + strings.Index(req.Text[search:], part)
+		start := utf16Len(req.Text[:idx])
+		end := start + utf16Len(part)
+		label := numberLabel(i+1, len(parts), "")
+		shift := utf16Len(label)
+
+		for _, e := range req.Entities {
+			from, to := max(e.Offset, start), min(e.Offset+e.Length, end)
+			if from < to {
+				e.Offset = from - start + shift
+				e.Length = to - from
+				entities[i] = append(entities[i], e)
+			}
+		}
+		parts[i] = label + part
+		search = idx + len(part)
+	}
+	return parts, entities
+}
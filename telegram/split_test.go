@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts SplitOptions
+		want []string
+	}{
+		{
+			name: "short text unchanged",
+			text: "xin chào",
+			opts: SplitOptions{Limit: 20},
+			want: []string{"xin chào"},
+		},
+		{
+			name: "prefer paragraph boundary",
+			text: "aaaa bbbb\n\ncccc dddd",
+			opts: SplitOptions{Limit: 15},
+			want: []string{"aaaa bbbb", "cccc dddd"},
+		},
+		{
+			name: "prefer line over space",
+			text: "aaa bbb\nccc ddd eee",
+			opts: SplitOptions{Limit: 12},
+			want: []string{"aaa bbb", "ccc ddd eee"},
+		},
+		{
+			name: "hard cut without separator",
+			text: "abcdefghij",
+			opts: SplitOptions{Limit: 4},
+			want: []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name: "emoji counts as two utf-16 units",
+			text: "😀😀😀",
+			opts: SplitOptions{Limit: 4},
+			want: []string{"😀😀", "😀"},
+		},
+		{
+			name: "numbered parts",
+			text: "aaaa bbbb cccc dddd eeee ffff gggg hhhh",
+			opts: SplitOptions{Limit: 30, Numbered: true},
+			want: []string{"(1/4)\naaaa bbbb", "(2/4)\ncccc dddd", "(3/4)\neeee ffff", "(4/4)\ngggg hhhh"},
+		},
+		{
+			name: "html tag reopened in next part",
+			text: "<b>" + strings.Repeat("x", 120) + "</b>",
+			opts: SplitOptions{Limit: 100, ParseMode: ParseModeHTML},
+			want: []string{
+				"<b>" + strings.Repeat("x", 33) + "</b>",
+				"<b>" + strings.Repeat("x", 33) + "</b>",
+				"<b>" + strings.Repeat("x", 33) + "</b>",
+				"<b>" + strings.Repeat("x", 21) + "</b>",
+			},
+		},
+		{
+			name: "markdownv2 bold reopened",
+			text: "*đậm " + strings.Repeat("z", 120) + "*",
+			opts: SplitOptions{Limit: 100, ParseMode: ParseModeMarkdownV2},
+			want: []string{
+				"*đậm " + strings.Repeat("z", 31) + "*",
+				"*" + strings.Repeat("z", 35) + "*",
+				"*" + strings.Repeat("z", 35) + "*",
+				"*" + strings.Repeat("z", 19) + "*",
+			},
+		},
+		{
+			name: "markdownv2 code block reopened with language",
+			text: "```go\n" + strings.Repeat("y", 100) + "```",
+			opts: SplitOptions{Limit: 100, ParseMode: ParseModeMarkdownV2},
+			want: []string{
+				"```go\n" + strings.Repeat("y", 30) + "\n```",
+				"```go\n" + strings.Repeat("y", 30) + "\n```",
+				"```go\n" + strings.Repeat("y", 30) + "\n```",
+				"```go\n" + strings.Repeat("y", 10) + "```",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitMessage(tt.text, tt.opts)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("SplitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMessageInvariants(t *testing.T) {
+	text := strings.Repeat("Tiếng Việt có dấu 😀 và emoji. ", 400)
+	for _, limit := range []int{1, 2, 3, 7, 100, MaxMessageLength} {
+		parts := SplitMessage(text, SplitOptions{Limit: limit})
+		var joined strings.Builder
+		for _, p := range parts {
+			if !utf8.ValidString(p) {
+				t.Fatalf("limit %d: part is not valid UTF-8: %q", limit, p)
+			}
+			// emoji 2 unit vẫn được lấy nguyên khi limit = 1
+			if n := utf16Len(p); n > limit && utf8.RuneCountInString(p) > 1 {
+				t.Fatalf("limit %d: part has %d units", limit, n)
+			}
+			joined.WriteString(p)
+		}
+		// chỉ ký tự phân tách ở chỗ cắt bị bỏ
+		if strings.ReplaceAll(joined.String(), " ", "") != strings.ReplaceAll(text, " ", "") {
+			t.Fatalf("limit %d: content lost after split", limit)
+		}
+	}
+}
+
+// Limit nhỏ kèm parse_mode từng làm budget = 0 và lặp vô hạn
+func TestSplitMessageTinyLimitTerminates(t *testing.T) {
+	for _, mode := range []string{"", ParseModeHTML, ParseModeMarkdownV2} {
+		for limit := 1; limit < 8; limit++ {
+			parts := SplitMessage("abc😀def ghi\\_jkl", SplitOptions{Limit: limit, ParseMode: mode, Numbered: true})
+			if len(parts) == 0 {
+				t.Fatalf("mode %q limit %d: no parts", mode, limit)
+			}
+		}
+	}
+}
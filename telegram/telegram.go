@@ -18,21 +18,242 @@ type User struct {
 }
 
 type Chat struct {
-	ID   int64  `json:"id"`
-	Type string `json:"type,omitempty"`
+	ID        int64  `json:"id"`
+	Type      string `json:"type,omitempty"` // private, group, supergroup, channel
+	Title     string `json:"title,omitempty"`
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	IsForum   bool   `json:"is_forum,omitempty"`
 }
 
 type Message struct {
-	MessageID int    `json:"message_id"`
-	From      *User  `json:"from,omitempty"`
-	Chat      Chat   `json:"chat"`
-	Date      int    `json:"date"`
-	Text      string `json:"text,omitempty"`
+	MessageID       int                   `json:"message_id"`
+	MessageThreadID int                   `json:"message_thread_id,omitempty"`
+	From            *User                 `json:"from,omitempty"`
+	SenderChat      *Chat                 `json:"sender_chat,omitempty"` // gửi thay mặt channel/group
+	Chat            Chat                  `json:"chat"`
+	Date            int                   `json:"date"`
+	EditDate        int                   `json:"edit_date,omitempty"`
+	ViaBot          *User                 `json:"via_bot,omitempty"`
+	ReplyToMessage  *Message              `json:"reply_to_message,omitempty"`
+	MediaGroupID    string                `json:"media_group_id,omitempty"`
+	Text            string                `json:"text,omitempty"`
+	Entities        []MessageEntity       `json:"entities,omitempty"`
+	ReplyMarkup     *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+
+	// File đính kèm; tải về bằng TelegramClient.DownloadFile(file_id)
+	Photo           []PhotoSize     `json:"photo,omitempty"` // nhiều kích thước, cái cuối là lớn nhất
+	Document        *Document       `json:"document,omitempty"`
+	Audio           *Audio          `json:"audio,omitempty"`
+	Video           *Video          `json:"video,omitempty"`
+	Voice           *Voice          `json:"voice,omitempty"`
+	Animation       *Animation      `json:"animation,omitempty"`
+	VideoNote       *VideoNote      `json:"video_note,omitempty"`
+	Sticker         *Sticker        `json:"sticker,omitempty"`
+	Caption         string          `json:"caption,omitempty"`
+	CaptionEntities []MessageEntity `json:"caption_entities,omitempty"`
+
+	Contact  *Contact  `json:"contact,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Poll     *Poll     `json:"poll,omitempty"`
+
+	// Service message
+	NewChatMembers  []User   `json:"new_chat_members,omitempty"`
+	LeftChatMember  *User    `json:"left_chat_member,omitempty"`
+	NewChatTitle    string   `json:"new_chat_title,omitempty"`
+	PinnedMessage   *Message `json:"pinned_message,omitempty"`
+	MigrateToChatID int64    `json:"migrate_to_chat_id,omitempty"`
+}
+
+// AttachmentFileID trả về file_id của file đính kèm trong message ("" nếu không có).
+// Với ảnh lấy kích thước lớn nhất.
+func (m *Message) AttachmentFileID() string {
+	switch {
+	case len(m.Photo) > 0:
+		return m.Photo[len(m.Photo)-1].FileID
+	case m.Document != nil:
+		return m.Document.FileID
+	case m.Audio != nil:
+		return m.Audio.FileID
+	case m.Video != nil:
+		return m.Video.FileID
+	case m.Voice != nil:
+		return m.Voice.FileID
+	case m.Animation != nil:
+		return m.Animation.FileID
+	case m.VideoNote != nil:
+		return m.VideoNote.FileID
+	case m.Sticker != nil:
+		return m.Sticker.FileID
+	}
+	return ""
+}
+
+// ---- Media ----
+
+type PhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+type Document struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MimeType     string     `json:"mime_type,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
+}
+
+type Audio struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Duration     int        `json:"duration"`
+	Performer    string     `json:"performer,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MimeType     string     `json:"mime_type,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+}
+
+type Video struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	Duration     int        `json:"duration"`
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MimeType     string     `json:"mime_type,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
+}
+
+type Voice struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Duration     int    `json:"duration"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+type Animation struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	Duration     int        `json:"duration"`
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MimeType     string     `json:"mime_type,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
+}
+
+type VideoNote struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Length       int        `json:"length"`
+	Duration     int        `json:"duration"`
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
 }
+
+type Sticker struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Type         string `json:"type"` // regular, mask, custom_emoji
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	IsAnimated   bool   `json:"is_animated"`
+	IsVideo      bool   `json:"is_video"`
+	Emoji        string `json:"emoji,omitempty"`
+	SetName      string `json:"set_name,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+type Contact struct {
+	PhoneNumber string `json:"phone_number"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name,omitempty"`
+	UserID      int64  `json:"user_id,omitempty"`
+}
+
+type Location struct {
+	Latitude           float64 `json:"latitude"`
+	Longitude          float64 `json:"longitude"`
+	HorizontalAccuracy float64 `json:"horizontal_accuracy,omitempty"`
+	LivePeriod         int     `json:"live_period,omitempty"`
+}
+
+// File là kết quả của getFile; FilePath dùng để tải qua /file/bot<token>/<file_path>
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	FilePath     string `json:"file_path,omitempty"`
+}
+
 // ---- Request structs ----
+
+// MessageEntity mô tả một đoạn định dạng trong text (offset/length tính theo UTF-16)
+type MessageEntity struct {
+	Type          string `json:"type"` // bold, italic, code, pre, text_link, ...
+	Offset        int    `json:"offset"`
+	Length        int    `json:"length"`
+	URL           string `json:"url,omitempty"`
+	User          *User  `json:"user,omitempty"`
+	Language      string `json:"language,omitempty"`
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"`
+}
+
+// LinkPreviewOptions điều khiển preview của link đầu tiên trong message
+type LinkPreviewOptions struct {
+	IsDisabled       bool   `json:"is_disabled,omitempty"`
+	URL              string `json:"url,omitempty"`
+	PreferSmallMedia bool   `json:"prefer_small_media,omitempty"`
+	PreferLargeMedia bool   `json:"prefer_large_media,omitempty"`
+	ShowAboveText    bool   `json:"show_above_text,omitempty"`
+}
+
+// ReplyParameters mô tả message được trả lời
+type ReplyParameters struct {
+	MessageID                int         `json:"message_id"`
+	ChatID                   interface{} `json:"chat_id,omitempty"`
+	AllowSendingWithoutReply bool        `json:"allow_sending_without_reply,omitempty"`
+	Quote                    string      `json:"quote,omitempty"`
+	QuoteParseMode           string      `json:"quote_parse_mode,omitempty"`
+}
+
 type SendMessageRequest struct {
-	ChatID interface{} `json:"chat_id" form:"chat_id"`
-	Text   string      `json:"text" form:"text" binding:"required"`
+	ChatID              interface{}         `json:"chat_id" form:"chat_id"`
+	Text                string              `json:"text" form:"text" binding:"required"`
+	ParseMode           string              `json:"parse_mode,omitempty" form:"parse_mode" binding:"omitempty,oneof=HTML MarkdownV2 Markdown"`
+	Entities            []MessageEntity     `json:"entities,omitempty" form:"-"`
+	DisableNotification bool                `json:"disable_notification,omitempty" form:"disable_notification"`
+	ProtectContent      bool                `json:"protect_content,omitempty" form:"protect_content"`
+	ReplyToMessageID    int                 `json:"reply_to_message_id,omitempty" form:"reply_to_message_id"`
+	ReplyParameters     *ReplyParameters    `json:"reply_parameters,omitempty" form:"-"`
+	LinkPreviewOptions  *LinkPreviewOptions `json:"link_preview_options,omitempty" form:"-"`
+	// ReplyMarkup: *InlineKeyboardMarkup, *ReplyKeyboardMarkup, *ReplyKeyboardRemove hoặc *ForceReply
+	ReplyMarkup interface{} `json:"reply_markup,omitempty" form:"-"`
+	// DisableLinkPreview là cách viết tắt của link_preview_options.is_disabled (dùng được với form)
+	DisableLinkPreview bool `json:"disable_link_preview,omitempty" form:"disable_link_preview"`
+}
+
+// normalize chuyển các field viết tắt sang dạng Bot API hiện tại trước khi gửi
+func (r *SendMessageRequest) normalize() {
+	if r.DisableLinkPreview && r.LinkPreviewOptions == nil {
+		r.LinkPreviewOptions = &LinkPreviewOptions{IsDisabled: true}
+	}
+	r.DisableLinkPreview = false
+	if r.ReplyToMessageID != 0 && r.ReplyParameters == nil {
+		r.ReplyParameters = &ReplyParameters{MessageID: r.ReplyToMessageID}
+	}
+	r.ReplyToMessageID = 0
 }
 
 type SendMediaRequest struct {
@@ -48,10 +269,13 @@ type CallbackQuery struct {
 	Data            string   `json:"data"`
 }
 
-type Update struct {
-	UpdateID      int            `json:"update_id"`
-	Message       *Message       `json:"message,omitempty"`
-	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+// AnswerCallbackQueryRequest trả lời callback query (tắt vòng xoay loading trên nút)
+type AnswerCallbackQueryRequest struct {
+	CallbackQueryID string `json:"callback_query_id" form:"callback_query_id" binding:"required"`
+	Text            string `json:"text,omitempty" form:"text"`
+	ShowAlert       bool   `json:"show_alert,omitempty" form:"show_alert"`
+	URL             string `json:"url,omitempty" form:"url"`
+	CacheTime       int    `json:"cache_time,omitempty" form:"cache_time"`
 }
 
 type GetUpdatesResponse struct {
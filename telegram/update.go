@@ -0,0 +1,232 @@
+package telegram
+
+// Các loại update, trùng tên field trong JSON và giá trị dùng cho allowed_updates
+const (
+	UpdateMessage            = "message"
+	UpdateEditedMessage      = "edited_message"
+	UpdateChannelPost        = "channel_post"
+	UpdateEditedChannelPost  = "edited_channel_post"
+	UpdateInlineQuery        = "inline_query"
+	UpdateChosenInlineResult = "chosen_inline_result"
+	UpdateCallbackQuery      = "callback_query"
+	UpdatePoll               = "poll"
+	UpdatePollAnswer         = "poll_answer"
+	UpdateMyChatMember       = "my_chat_member"
+	UpdateChatMember         = "chat_member"
+	UpdateChatJoinRequest    = "chat_join_request"
+)
+
+// Update là một sự kiện Telegram gửi tới bot; mỗi update chỉ có đúng một field dữ liệu khác nil
+type Update struct {
+	UpdateID           int                 `json:"update_id"`
+	Message            *Message            `json:"message,omitempty"`
+	EditedMessage      *Message            `json:"edited_message,omitempty"`
+	ChannelPost        *Message            `json:"channel_post,omitempty"`
+	EditedChannelPost  *Message            `json:"edited_channel_post,omitempty"`
+	InlineQuery        *InlineQuery        `json:"inline_query,omitempty"`
+	ChosenInlineResult *ChosenInlineResult `json:"chosen_inline_result,omitempty"`
+	CallbackQuery      *CallbackQuery      `json:"callback_query,omitempty"`
+	Poll               *Poll               `json:"poll,omitempty"`
+	PollAnswer         *PollAnswer         `json:"poll_answer,omitempty"`
+	MyChatMember       *ChatMemberUpdated  `json:"my_chat_member,omitempty"`
+	ChatMember         *ChatMemberUpdated  `json:"chat_member,omitempty"`
+	ChatJoinRequest    *ChatJoinRequest    `json:"chat_join_request,omitempty"`
+}
+
+// Kind trả về loại update (UpdateMessage, UpdateCallbackQuery, ...), "" nếu không nhận ra
+func (u *Update) Kind() string {
+	switch {
+	case u.Message != nil:
+		return UpdateMessage
+	case u.EditedMessage != nil:
+		return UpdateEditedMessage
+	case u.ChannelPost != nil:
+		return UpdateChannelPost
+	case u.EditedChannelPost != nil:
+		return UpdateEditedChannelPost
+	case u.InlineQuery != nil:
+		return UpdateInlineQuery
+	case u.ChosenInlineResult != nil:
+		return UpdateChosenInlineResult
+	case u.CallbackQuery != nil:
+		return UpdateCallbackQuery
+	case u.Poll != nil:
+		return UpdatePoll
+	case u.PollAnswer != nil:
+		return UpdatePollAnswer
+	case u.MyChatMember != nil:
+		return UpdateMyChatMember
+	case u.ChatMember != nil:
+		return UpdateChatMember
+	case u.ChatJoinRequest != nil:
+		return UpdateChatJoinRequest
+	}
+	return ""
+}
+
+// AnyMessage trả về message của update message/edited_message/channel_post/edited_channel_post
+func (u *Update) AnyMessage() *Message {
+	switch {
+	case u.Message != nil:
+		return u.Message
+	case u.EditedMessage != nil:
+		return u.EditedMessage
+	case u.ChannelPost != nil:
+		return u.ChannelPost
+	case u.EditedChannelPost != nil:
+		return u.EditedChannelPost
+	}
+	return nil
+}
+
+// Sender trả về người tạo ra update (nil với channel_post, poll)
+func (u *Update) Sender() *User {
+	if msg := u.AnyMessage(); msg != nil {
+		return msg.From
+	}
+	switch {
+	case u.InlineQuery != nil:
+		return &u.InlineQuery.From
+	case u.ChosenInlineResult != nil:
+		return &u.ChosenInlineResult.From
+	case u.CallbackQuery != nil:
+		return &u.CallbackQuery.From
+	case u.PollAnswer != nil:
+		return u.PollAnswer.User
+	case u.MyChatMember != nil:
+		return &u.MyChatMember.From
+	case u.ChatMember != nil:
+		return &u.ChatMember.From
+	case u.ChatJoinRequest != nil:
+		return &u.ChatJoinRequest.From
+	}
+	return nil
+}
+
+// Chat trả về chat nơi update xảy ra (nil với inline query, poll...)
+func (u *Update) Chat() *Chat {
+	if msg := u.AnyMessage(); msg != nil {
+		return &msg.Chat
+	}
+	switch {
+	case u.CallbackQuery != nil && u.CallbackQuery.Message != nil:
+		return &u.CallbackQuery.Message.Chat
+	case u.MyChatMember != nil:
+		return &u.MyChatMember.Chat
+	case u.ChatMember != nil:
+		return &u.ChatMember.Chat
+	case u.ChatJoinRequest != nil:
+		return &u.ChatJoinRequest.Chat
+	}
+	return nil
+}
+
+// ---- Inline mode ----
+
+type InlineQuery struct {
+	ID       string    `json:"id"`
+	From     User      `json:"from"`
+	Query    string    `json:"query"`
+	Offset   string    `json:"offset"`
+	ChatType string    `json:"chat_type,omitempty"`
+	Location *Location `json:"location,omitempty"`
+}
+
+type ChosenInlineResult struct {
+	ResultID        string    `json:"result_id"`
+	From            User      `json:"from"`
+	Location        *Location `json:"location,omitempty"`
+	InlineMessageID string    `json:"inline_message_id,omitempty"`
+	Query           string    `json:"query"`
+}
+
+// ---- Poll ----
+
+type PollOption struct {
+	Text       string `json:"text"`
+	VoterCount int    `json:"voter_count"`
+}
+
+type Poll struct {
+	ID                    string       `json:"id"`
+	Question              string       `json:"question"`
+	Options               []PollOption `json:"options"`
+	TotalVoterCount       int          `json:"total_voter_count"`
+	IsClosed              bool         `json:"is_closed"`
+	IsAnonymous           bool         `json:"is_anonymous"`
+	Type                  string       `json:"type"` // regular, quiz
+	AllowsMultipleAnswers bool         `json:"allows_multiple_answers"`
+	CorrectOptionID       *int         `json:"correct_option_id,omitempty"`
+	Explanation           string       `json:"explanation,omitempty"`
+	OpenPeriod            int          `json:"open_period,omitempty"`
+	CloseDate             int64        `json:"close_date,omitempty"`
+}
+
+type PollAnswer struct {
+	PollID    string `json:"poll_id"`
+	VoterChat *Chat  `json:"voter_chat,omitempty"` // khi bình chọn ẩn danh thay mặt chat
+	User      *User  `json:"user,omitempty"`
+	OptionIDs []int  `json:"option_ids"` // rỗng = rút lại bình chọn
+}
+
+// ---- Chat member ----
+
+// ChatMember gộp các biến thể ChatMemberOwner/Administrator/Member/Restricted/Left/Banned,
+// phân biệt bằng Status
+type ChatMember struct {
+	Status      string `json:"status"` // creator, administrator, member, restricted, left, kicked
+	User        User   `json:"user"`
+	IsAnonymous bool   `json:"is_anonymous,omitempty"`
+	CustomTitle string `json:"custom_title,omitempty"`
+	UntilDate   int64  `json:"until_date,omitempty"`
+	IsMember    bool   `json:"is_member,omitempty"` // chỉ có với restricted
+
+	CanBeEdited         bool `json:"can_be_edited,omitempty"`
+	CanManageChat       bool `json:"can_manage_chat,omitempty"`
+	CanDeleteMessages   bool `json:"can_delete_messages,omitempty"`
+	CanRestrictMembers  bool `json:"can_restrict_members,omitempty"`
+	CanPromoteMembers   bool `json:"can_promote_members,omitempty"`
+	CanChangeInfo       bool `json:"can_change_info,omitempty"`
+	CanInviteUsers      bool `json:"can_invite_users,omitempty"`
+	CanPostMessages     bool `json:"can_post_messages,omitempty"`
+	CanEditMessages     bool `json:"can_edit_messages,omitempty"`
+	CanPinMessages      bool `json:"can_pin_messages,omitempty"`
+	CanSendMessages     bool `json:"can_send_messages,omitempty"`
+	CanSendMediaMessage bool `json:"can_send_media_messages,omitempty"`
+}
+
+// IsAdmin: creator hoặc administrator
+func (m *ChatMember) IsAdmin() bool {
+	return m.Status == "creator" || m.Status == "administrator"
+}
+
+type ChatInviteLink struct {
+	InviteLink              string `json:"invite_link"`
+	Creator                 User   `json:"creator"`
+	CreatesJoinRequest      bool   `json:"creates_join_request"`
+	IsPrimary               bool   `json:"is_primary"`
+	IsRevoked               bool   `json:"is_revoked"`
+	Name                    string `json:"name,omitempty"`
+	ExpireDate              int64  `json:"expire_date,omitempty"`
+	MemberLimit             int    `json:"member_limit,omitempty"`
+	PendingJoinRequestCount int    `json:"pending_join_request_count,omitempty"`
+}
+
+// ChatMemberUpdated: trạng thái thành viên thay đổi (my_chat_member là của chính bot)
+type ChatMemberUpdated struct {
+	Chat          Chat            `json:"chat"`
+	From          User            `json:"from"`
+	Date          int64           `json:"date"`
+	OldChatMember ChatMember      `json:"old_chat_member"`
+	NewChatMember ChatMember      `json:"new_chat_member"`
+	InviteLink    *ChatInviteLink `json:"invite_link,omitempty"`
+}
+
+type ChatJoinRequest struct {
+	Chat       Chat            `json:"chat"`
+	From       User            `json:"from"`
+	UserChatID int64           `json:"user_chat_id"`
+	Date       int64           `json:"date"`
+	Bio        string          `json:"bio,omitempty"`
+	InviteLink *ChatInviteLink `json:"invite_link,omitempty"`
+}
@@ -0,0 +1,239 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrFileTooLarge: file vượt giới hạn upload của Bot API cho loại media đó
+var ErrFileTooLarge = errors.New("telegram: file too large")
+
+// Giới hạn upload của Bot API chính thức: ảnh 10 MB, các loại khác 50 MB.
+// Bot API server tự host cho phép tới 2000 MB, chỉnh bằng WithMaxUploadSize.
+const (
+	maxPhotoUpload   = 10 << 20
+	defaultMaxUpload = 50 << 20
+)
+
+// WithMaxUploadSize đổi giới hạn upload cho một field ("photo", "video", ...)
+func WithMaxUploadSize(fieldName string, size int64) Option {
+	return func(c *TelegramClient) {
+		if c.uploadLimits == nil {
+			c.uploadLimits = map[string]int64{}
+		}
+		c.uploadLimits[fieldName] = size
+	}
+}
+
+// MaxUploadSize trả về số byte tối đa được upload cho field
+func (c *TelegramClient) MaxUploadSize(fieldName string) int64 {
+	if n, ok := c.uploadLimits[fieldName]; ok {
+		return n
+	}
+	if fieldName == "photo" {
+		return maxPhotoUpload
+	}
+	return defaultMaxUpload
+}
+
+// ProgressFunc nhận số byte đã gửi và tổng số byte (-1 nếu chưa biết trước)
+type ProgressFunc func(sent, total int64)
+
+type progressKey struct{}
+
+// ContextWithProgress gắn callback tiến độ upload vào ctx, dùng với các method ...Context
+func ContextWithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// uploadPart là một file trong multipart body; open được gọi lại ở mỗi lần retry
+type uploadPart struct {
+	field    string
+	filename string
+	size     int64 // -1 nếu không biết
+	limit    int64
+	open     func() (io.ReadCloser, error)
+}
+
+// streamMultipart dựng multipart body qua io.Pipe: file được đọc và gửi dần,
+// không bao giờ nằm trọn trong RAM. Lỗi khi đọc file sẽ làm request bị huỷ.
+func streamMultipart(ctx context.Context, fields map[string]string, parts []uploadPart) (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	progress := progressFromContext(ctx)
+
+	total := int64(0)
+	for _, p := range parts {
+		if p.size < 0 {
+			total = -1
+			break
+		}
+		total += p.size
+	}
+
+	go func() {
+		sent := int64(0)
+		err := func() error {
+			for k, v := range fields {
+				if err := writer.WriteField(k, v); err != nil {
+					return err
+				}
+			}
+			for _, p := range parts {
+				dst, err := writer.CreateFormFile(p.field, p.filename)
+				if err != nil {
+					return err
+				}
+				src, err := p.open()
+				if err != nil {
+					return err
+				}
+				var r io.Reader = src
+				if p.limit > 0 {
+					r = &limitedReader{r: src, remaining: p.limit, field: p.field}
+				}
+				if progress != nil {
+					r = &progressReader{r: r, sent: &sent, total: total, fn: progress}
+				}
+				_, err = io.Copy(dst, r)
+				src.Close()
+				if err != nil {
+					return err
+				}
+			}
+			return writer.Close()
+		}()
+		pw.CloseWithError(err) // err nil → reader nhận io.EOF
+	}()
+	return pr, writer.FormDataContentType()
+}
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	field     string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, fmt.Errorf("%w: %s exceeds upload limit", ErrFileTooLarge, l.field)
+	}
+	return n, err
+}
+
+type progressReader struct {
+	r     io.Reader
+	sent  *int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		*p.sent += int64(n)
+		p.fn(*p.sent, p.total)
+	}
+	return n, err
+}
+
+// postMultipart gửi multipart đã stream. replayable=false khi nguồn chỉ đọc được một lần
+// (vd body của request đang nhận), khi đó không retry.
+func (c *TelegramClient) postMultipart(ctx context.Context, method string, chatID int64, fields map[string]string, parts []uploadPart, replayable bool) ([]byte, int, error) {
+	policy := c.retry
+	if !replayable {
+		policy.MaxAttempts = 1
+	}
+	return c.doWithPolicy(ctx, method, chatID, policy, func() (*http.Request, error) {
+		body, contentType := streamMultipart(ctx, fields, parts)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(method), body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		return req, nil
+	})
+}
+
+// localUploadPart mô tả file trên disk gửi ở field, kiểm tra giới hạn dung lượng của loại kind
+func (c *TelegramClient) localUploadPart(field, kind, path string) (uploadPart, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return uploadPart{}, fmt.Errorf("stat file error: %v", err)
+	}
+	limit := c.MaxUploadSize(kind)
+	if info.Size() > limit {
+		return uploadPart{}, fmt.Errorf("%w: %s is %d bytes, max %d for %s", ErrFileTooLarge, filepath.Base(path), info.Size(), limit, kind)
+	}
+	return uploadPart{
+		field:    field,
+		filename: filepath.Base(path),
+		size:     info.Size(),
+		limit:    limit,
+		open:     func() (io.ReadCloser, error) { return os.Open(path) },
+	}, nil
+}
+
+func uploadFields(chatID int64, extra map[string]string) map[string]string {
+	fields := map[string]string{"chat_id": strconv.FormatInt(chatID, 10)}
+	for k, v := range extra {
+		fields[k] = v
+	}
+	return fields
+}
+
+// uploadFile upload file local qua multipart/form-data (stream từ disk)
+func (c *TelegramClient) uploadFile(ctx context.Context, method string, chatID int64, fieldName, filePath string, extra map[string]string) ([]byte, int, error) {
+	part, err := c.localUploadPart(fieldName, fieldName, filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	respBody, status, err := c.postMultipart(ctx, method, chatID, uploadFields(chatID, extra), []uploadPart{part}, true)
+	if err != nil {
+		return respBody, status, err
+	}
+	fmt.Printf("✅ %s sent: %s\n", method, filePath)
+	return respBody, status, nil
+}
+
+// SendMediaReaderRawContext upload media đọc thẳng từ r (vd part của multipart request đang nhận)
+// mà không lưu ra disk. size = -1 nếu không biết. Vì r chỉ đọc được một lần nên không retry.
+func (c *TelegramClient) SendMediaReaderRawContext(ctx context.Context, method string, chatID int64, fieldName, filename string, r io.Reader, size int64, extra map[string]string) ([]byte, int, error) {
+	if c.Token == "" {
+		return nil, 0, fmt.Errorf("⚠️ Telegram token empty")
+	}
+	limit := c.MaxUploadSize(fieldName)
+	if size > limit {
+		return nil, 0, fmt.Errorf("%w: %s is %d bytes, max %d for %s", ErrFileTooLarge, filename, size, limit, fieldName)
+	}
+	used := false
+	part := uploadPart{
+		field:    fieldName,
+		filename: filename,
+		size:     size,
+		limit:    limit,
+		open: func() (io.ReadCloser, error) {
+			if used {
+				return nil, fmt.Errorf("%s: stream already consumed", method)
+			}
+			used = true
+			return io.NopCloser(r), nil
+		},
+	}
+	return c.postMultipart(ctx, method, chatID, uploadFields(chatID, extra), []uploadPart{part}, false)
+}
@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	content := make([]byte, 100<<10)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	var lastSent, lastTotal int64
+	ctx := ContextWithProgress(context.Background(), func(sent, total int64) {
+		calls++
+		lastSent, lastTotal = sent, total
+	})
+	c := NewTelegramClient("TOKEN", WithBaseURL(srv.URL), WithoutRateLimit())
+	if _, _, err := c.SendDocumentRawContext(ctx, 1, path, ""); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || lastSent != int64(len(content)) || lastTotal != int64(len(content)) {
+		t.Fatalf("progress calls=%d last=%d/%d, want %d/%d", calls, lastSent, lastTotal, len(content), len(content))
+	}
+}
@@ -0,0 +1,121 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// SetWebhookParams là tham số của setWebhook
+type SetWebhookParams struct {
+	URL                string   `json:"url" form:"url" binding:"required,url"`
+	SecretToken        string   `json:"secret_token,omitempty" form:"secret_token"` // Telegram gửi lại ở header X-Telegram-Bot-Api-Secret-Token
+	AllowedUpdates     []string `json:"allowed_updates,omitempty" form:"allowed_updates"`
+	MaxConnections     int      `json:"max_connections,omitempty" form:"max_connections" binding:"omitempty,min=1,max=100"`
+	DropPendingUpdates bool     `json:"drop_pending_updates,omitempty" form:"drop_pending_updates"`
+	IPAddress          string   `json:"ip_address,omitempty" form:"ip_address" binding:"omitempty,ip"`
+	// Certificate là đường dẫn file public key (PEM) khi dùng chứng chỉ tự ký
+	Certificate string `json:"-" form:"-"`
+}
+
+// WebhookInfo là kết quả của getWebhookInfo
+type WebhookInfo struct {
+	URL                          string   `json:"url"`
+	HasCustomCertificate         bool     `json:"has_custom_certificate"`
+	PendingUpdateCount           int      `json:"pending_update_count"`
+	IPAddress                    string   `json:"ip_address,omitempty"`
+	LastErrorDate                int64    `json:"last_error_date,omitempty"`
+	LastErrorMessage             string   `json:"last_error_message,omitempty"`
+	LastSynchronizationErrorDate int64    `json:"last_synchronization_error_date,omitempty"`
+	MaxConnections               int      `json:"max_connections,omitempty"`
+	AllowedUpdates               []string `json:"allowed_updates,omitempty"`
+}
+
+var secretTokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// Validate kiểm tra các ràng buộc Telegram đặt ra cho setWebhook
+func (p SetWebhookParams) Validate() error {
+	if p.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if p.SecretToken != "" && !secretTokenRe.MatchString(p.SecretToken) {
+		return fmt.Errorf("secret_token must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+	if p.MaxConnections < 0 || p.MaxConnections > 100 {
+		return fmt.Errorf("max_connections must be between 1-100")
+	}
+	return nil
+}
+
+// SetWebhookRawContext đăng ký webhook. Có Certificate thì gửi multipart kèm file, không thì gửi JSON.
+func (c *TelegramClient) SetWebhookRawContext(ctx context.Context, p SetWebhookParams) ([]byte, int, error) {
+	if err := p.Validate(); err != nil {
+		return nil, 0, err
+	}
+	if p.Certificate == "" {
+		return c.postJSON(ctx, "setWebhook", p)
+	}
+
+	fields := map[string]string{"url": p.URL}
+	if p.SecretToken != "" {
+		fields["secret_token"] = p.SecretToken
+	}
+	if p.AllowedUpdates != nil {
+		allowed, err := json.Marshal(p.AllowedUpdates)
+		if err != nil {
+			return nil, 0, fmt.Errorf("marshal error: %v", err)
+		}
+		fields["allowed_updates"] = string(allowed)
+	}
+	if p.MaxConnections > 0 {
+		fields["max_connections"] = strconv.Itoa(p.MaxConnections)
+	}
+	if p.DropPendingUpdates {
+		fields["drop_pending_updates"] = "true"
+	}
+	if p.IPAddress != "" {
+		fields["ip_address"] = p.IPAddress
+	}
+
+	part, err := c.localUploadPart("certificate", "certificate", p.Certificate)
+	if err != nil {
+		return nil, 0, err
+	}
+	return c.postMultipart(ctx, "setWebhook", 0, fields, []uploadPart{part}, true)
+}
+
+func (c *TelegramClient) SetWebhookRaw(p SetWebhookParams) ([]byte, int, error) {
+	return c.SetWebhookRawContext(context.Background(), p)
+}
+
+// DeleteWebhookRawContext gỡ webhook (cần thiết trước khi chuyển sang getUpdates)
+func (c *TelegramClient) DeleteWebhookRawContext(ctx context.Context, dropPendingUpdates bool) ([]byte, int, error) {
+	return c.postJSON(ctx, "deleteWebhook", map[string]interface{}{"drop_pending_updates": dropPendingUpdates})
+}
+
+func (c *TelegramClient) DeleteWebhookRaw(dropPendingUpdates bool) ([]byte, int, error) {
+	return c.DeleteWebhookRawContext(context.Background(), dropPendingUpdates)
+}
+
+// GetWebhookInfoContext lấy trạng thái webhook hiện tại (url, số update đang chờ, lỗi gần nhất...)
+func (c *TelegramClient) GetWebhookInfoContext(ctx context.Context) (*WebhookInfo, error) {
+	respBody, _, err := c.postJSON(ctx, "getWebhookInfo", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Ok     bool        `json:"ok"`
+		Result WebhookInfo `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	return &result.Result, nil
+}
+
+func (c *TelegramClient) GetWebhookInfo() (*WebhookInfo, error) {
+	return c.GetWebhookInfoContext(context.Background())
+}
@@ -0,0 +1,121 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"dnk.com/hoc-golang/middleware"
+)
+
+// ErrQueueFull: hàng đợi update đã đầy, nên trả non-2xx để Telegram gửi lại sau
+var ErrQueueFull = errors.New("telegram: update queue is full")
+
+// ErrPoolStopped: worker pool đã dừng, không nhận thêm update
+var ErrPoolStopped = errors.New("telegram: update worker pool stopped")
+
+// UpdateWorkerPool xử lý update bất đồng bộ bằng nhiều worker.
+// Update cùng chat luôn vào cùng một worker nên được xử lý đúng thứ tự nhận.
+type UpdateWorkerPool struct {
+	handler UpdateHandlerFunc
+	queues  []chan *Update
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewUpdateWorkerPool tạo pool với workers goroutine, mỗi worker có hàng đợi queueSize update.
+// Các worker chạy ngay, gọi Stop khi tắt server.
+func NewUpdateWorkerPool(handler UpdateHandlerFunc, workers, queueSize int) *UpdateWorkerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &UpdateWorkerPool{handler: handler, ctx: ctx, cancel: cancel}
+	for i := 0; i < workers; i++ {
+		queue := make(chan *Update, queueSize)
+		p.queues = append(p.queues, queue)
+		p.wg.Add(1)
+		go p.work(queue)
+	}
+	return p
+}
+
+// Submit đưa update vào hàng đợi, không chặn; trả ErrQueueFull nếu worker tương ứng đang quá tải
+func (p *UpdateWorkerPool) Submit(u *Update) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrPoolStopped
+	}
+
+	key := int64(u.UpdateID)
+	if chat := u.Chat(); chat != nil {
+		key = chat.ID
+	}
+	if key < 0 {
+		key = -key
+	}
+	select {
+	case p.queues[key%int64(len(p.queues))] <- u:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop ngừng nhận update, chờ xử lý hết hàng đợi; khi ctx hết hạn thì huỷ các handler đang chạy
+func (p *UpdateWorkerPool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopped = true
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return fmt.Errorf("update worker pool stop: %w", ctx.Err())
+	}
+}
+
+func (p *UpdateWorkerPool) work(queue chan *Update) {
+	defer p.wg.Done()
+	for u := range queue {
+		p.handle(u)
+	}
+}
+
+// handle gọi handler, lỗi/panic chỉ được log như Poller
+func (p *UpdateWorkerPool) handle(u *Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			middleware.LogTelegramError("❌ Update handler panic", fmt.Errorf("%v", r), map[string]interface{}{"update_id": u.UpdateID})
+		}
+	}()
+	if err := p.handler(p.ctx, u); err != nil {
+		middleware.LogTelegramError("❌ Update handler failed", err, map[string]interface{}{"update_id": u.UpdateID, "kind": u.Kind()})
+	}
+}
@@ -68,6 +68,14 @@ func HandleValidationErrors(err error) gin.H {
 				errors[fieldPath] = fmt.Sprintf("%s phải đúng định dạng email", fieldPath)
 			case "datetime":
 				errors[fieldPath] = fmt.Sprintf("%s phải theo đúng định dạng YYYY-MM-DD", fieldPath)
+			case "timezone":
+				errors[fieldPath] = fmt.Sprintf("%s phải là tên múi giờ IANA, vd Asia/Ho_Chi_Minh", fieldPath)
+			case "cron":
+				errors[fieldPath] = fmt.Sprintf("%s phải là lịch hợp lệ: cron 5/6 field, @daily, @every 5m...", fieldPath)
+			case "job_name":
+				errors[fieldPath] = fmt.Sprintf("%s chỉ được chứa a-z, 0-9, _ và -, tối đa 64 ký tự", fieldPath)
+			case "job_params", "job_steps":
+				errors[fieldPath] = fmt.Sprintf("%s không hợp lệ: %s", fieldPath, e.Param())
 			case "file_ext":
 				allowedValues := strings.Join(strings.Split(e.Param(), " "), ",")
 				errors[fieldPath] = fmt.Sprintf("%s chỉ cho phép những file có extension %s", fieldPath, allowedValues)